@@ -0,0 +1,222 @@
+package fetchup
+
+import (
+	"bufio"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Auth adds credentials to a request.
+// It's only called for the requests whose host matches the key of the auth in [Fetchup.Auth],
+// so the credentials won't leak to a different host on redirect.
+type Auth interface {
+	Authorize(req *http.Request) error
+}
+
+// HeaderAuth sets static headers, such as "X-JFrog-Art-Api".
+type HeaderAuth http.Header
+
+var _ Auth = HeaderAuth{}
+
+func (h HeaderAuth) Authorize(req *http.Request) error {
+	for k, list := range h {
+		req.Header.Del(k)
+		for _, v := range list {
+			req.Header.Add(k, v)
+		}
+	}
+	return nil
+}
+
+// BearerAuth sets the bearer token to the Authorization header.
+type BearerAuth struct {
+	// Token to use, if it's empty the value of the env var Env will be used.
+	Token string
+
+	// Env is the name of the env var that holds the token, such as "GITHUB_TOKEN".
+	Env string
+}
+
+var _ Auth = BearerAuth{}
+
+// Authorize does nothing if the token is empty, so that public resources are still accessible.
+func (a BearerAuth) Authorize(req *http.Request) error {
+	token := a.Token
+	if token == "" && a.Env != "" {
+		token = os.Getenv(a.Env)
+	}
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return nil
+}
+
+// BasicAuth sets the username and password to the Authorization header.
+type BasicAuth struct {
+	User     string
+	Password string
+}
+
+var _ Auth = BasicAuth{}
+
+func (a BasicAuth) Authorize(req *http.Request) error {
+	req.SetBasicAuth(a.User, a.Password)
+	return nil
+}
+
+// LoadNetrc parses the netrc file and returns the [BasicAuth] of each machine in it.
+// If path is empty, $NETRC or the .netrc (_netrc on Windows) under the home dir will be used.
+// A missing file returns an empty map. The "default" entry is ignored to avoid leaking credentials.
+func LoadNetrc(path string) (map[string]Auth, error) {
+	if path == "" {
+		path = netrcPath()
+	}
+
+	auth := map[string]Auth{}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return auth, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	machine := ""
+	var cur *BasicAuth
+
+	flush := func() {
+		if machine != "" && cur != nil {
+			auth[machine] = *cur
+		}
+		machine = ""
+		cur = nil
+	}
+
+	sc := bufio.NewScanner(f)
+	inMacro := false
+	key := "" // the keyword that waits for its value, the value can be on the next line
+	for sc.Scan() {
+		line := sc.Text()
+
+		// macdef body ends with an empty line
+		if inMacro {
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+
+		for _, field := range strings.Fields(line) {
+			if key != "" {
+				switch key {
+				case "machine":
+					machine = field
+					cur = &BasicAuth{}
+				case "login":
+					if cur != nil {
+						cur.User = field
+					}
+				case "password":
+					if cur != nil {
+						cur.Password = field
+					}
+				}
+				key = ""
+				continue
+			}
+
+			switch field {
+			case "machine":
+				flush()
+				key = field
+			case "login", "password", "account":
+				key = field
+			case "default":
+				flush()
+			case "macdef":
+				inMacro = true
+			}
+
+			if inMacro {
+				break
+			}
+		}
+	}
+	flush()
+
+	return auth, sc.Err()
+}
+
+func netrcPath() string {
+	if p := os.Getenv("NETRC"); p != "" {
+		return p
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	name := ".netrc"
+	if runtime.GOOS == "windows" {
+		name = "_netrc"
+	}
+
+	return filepath.Join(home, name)
+}
+
+// lookupAuth matches the host with port first, then the hostname without port.
+func lookupAuth(list map[string]Auth, u *url.URL) Auth {
+	if a, has := list[u.Host]; has {
+		return a
+	}
+	return list[u.Hostname()]
+}
+
+// authTransport authorizes each request hop, the Go http client reuses the headers of the original request
+// for redirects, so setting the credentials on the original request may leak them to another host.
+type authTransport struct {
+	base http.RoundTripper
+	auth map[string]Auth
+}
+
+var _ http.RoundTripper = (*authTransport)(nil)
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	a := lookupAuth(t.auth, req.URL)
+	if a == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+
+	err := a.Authorize(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// client returns the http client with the [Fetchup.Auth] applied.
+func (fu *Fetchup) client() *http.Client {
+	if len(fu.Auth) == 0 {
+		return fu.HttpClient
+	}
+
+	c := *fu.HttpClient
+
+	base := c.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	c.Transport = &authTransport{base, fu.Auth}
+
+	return &c
+}
@@ -0,0 +1,88 @@
+package fetchup_test
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/ysmood/fetchup"
+	"github.com/ysmood/got"
+)
+
+func TestAuth(t *testing.T) {
+	g, s, data := setup(t)
+
+	other := g.Serve()
+	leaked := ""
+	other.Mux.HandleFunc("/", func(rw http.ResponseWriter, r *http.Request) {
+		leaked = r.Header.Get("Authorization") + r.Header.Get("X-Api-Key")
+		g.E(rw.Write(data))
+	})
+
+	s.Mux.HandleFunc("/private/", func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("X-Api-Key") != "key" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.Redirect(rw, r, other.URL("/file"), http.StatusFound)
+	})
+
+	g.Setenv("FETCHUP_TEST_TOKEN", "secret")
+
+	p := filepath.Join(getTmpDir(g), "t.out")
+
+	fu := fetchup.New(s.URL("/private/")).WithSaveTo(p)
+	fu.Logger = log.New(io.Discard, "", 0)
+	fu.SpeedPacketSize = 100
+	fu.Auth = map[string]fetchup.Auth{
+		s.HostURL.Host: authList{
+			fetchup.BearerAuth{Env: "FETCHUP_TEST_TOKEN"},
+			fetchup.HeaderAuth{"X-Api-Key": {"key"}},
+		},
+	}
+	g.E(fu.Fetch())
+
+	g.Eq(g.Read(p).Bytes(), data)
+	g.Eq(leaked, "")
+}
+
+type authList []fetchup.Auth
+
+func (l authList) Authorize(req *http.Request) error {
+	for _, a := range l {
+		if err := a.Authorize(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestLoadNetrc(t *testing.T) {
+	g := got.T(t)
+
+	p := filepath.Join(getTmpDir(g), ".netrc")
+	g.WriteFile(p, `machine example.com login a password b
+macdef init
+machine in-macro.com login x password y
+
+default login c password d
+
+machine
+  artifactory.local
+  login e
+  password f
+`)
+
+	auth, err := fetchup.LoadNetrc(p)
+	g.E(err)
+	g.Eq(auth, map[string]fetchup.Auth{
+		"example.com":       fetchup.BasicAuth{User: "a", Password: "b"},
+		"artifactory.local": fetchup.BasicAuth{User: "e", Password: "f"},
+	})
+
+	auth, err = fetchup.LoadNetrc(filepath.Join(getTmpDir(g), "not-exists"))
+	g.E(err)
+	g.Len(auth, 0)
+}
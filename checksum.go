@@ -0,0 +1,59 @@
+package fetchup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+)
+
+// ErrChecksum is returned when the digest of the downloaded content doesn't match the expected one.
+type ErrChecksum struct {
+	URL      string
+	Expected string
+	Actual   string
+}
+
+func (e *ErrChecksum) Error() string {
+	return fmt.Sprintf("Checksum mismatch, expected %s but got %s: %s", e.Expected, e.Actual, e.URL)
+}
+
+// verifyReader checks the sha256 digest of the content when it reaches EOF, it's not checked if expected is empty.
+type verifyReader struct {
+	url      string
+	r        io.ReadCloser
+	h        hash.Hash
+	expected string
+}
+
+func newVerifyReader(u string, r io.ReadCloser, expected string) *verifyReader {
+	return &verifyReader{
+		url:      u,
+		r:        r,
+		h:        sha256.New(),
+		expected: strings.ToLower(strings.TrimPrefix(expected, "sha256:")),
+	}
+}
+
+func (v *verifyReader) Read(p []byte) (int, error) {
+	n, err := v.r.Read(p)
+	_, _ = v.h.Write(p[:n])
+
+	if err == io.EOF && v.expected != "" {
+		if actual := v.sum(); actual != v.expected {
+			return n, &ErrChecksum{v.url, v.expected, actual}
+		}
+	}
+
+	return n, err
+}
+
+func (v *verifyReader) sum() string {
+	return hex.EncodeToString(v.h.Sum(nil))
+}
+
+func (v *verifyReader) Close() error {
+	return v.r.Close()
+}
@@ -0,0 +1,270 @@
+// Command fetchup downloads files from the fastest mirror and installs tools, it wraps the fetchup library
+// for the build scripts that aren't written in Go.
+//
+//	fetchup get <url...> [-o dir] [-sha256 digest]
+//	fetchup install <tool>[@version]... [-dir dir] [-j concurrency] [-os os] [-arch arch]
+//	fetchup sync [-manifest tools.json] [-lockfile tools.lock.json] [-dir dir] [-update] [-frozen] [tool...]
+//	fetchup list [-dir dir]
+//	fetchup uninstall <tool> [-dir dir]
+//	fetchup cache clean
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ysmood/fetchup"
+	"github.com/ysmood/fetchup/pkg"
+	"github.com/ysmood/fetchup/pkg/golang_migrate"
+	"github.com/ysmood/fetchup/pkg/golangci_lint"
+)
+
+// installers are the builtin tools for the install command.
+var installers = map[string]func(pkg.Options) pkg.Options{
+	"golangci-lint": golangci_lint.Defaults,
+	"migrate":       golang_migrate.Defaults,
+}
+
+const usage = `Usage:
+  fetchup get <url...> [-o dir] [-sha256 digest]     download from the fastest url and extract it
+  fetchup install <tool>[@version]... [-dir dir]     install the builtin tools: %s
+  fetchup sync [-manifest file] [-update] [tool...]  install the tools in the manifest with the lockfile
+  fetchup list [-dir dir]                            list the installed tools
+  fetchup uninstall <tool> [-dir dir]                remove an installed tool
+  fetchup cache clean                                remove the temp files of the downloads
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run returns the exit code, 2 means a usage error.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		printUsage(stderr)
+		return 2
+	}
+
+	logger := log.New(stderr, "", log.LstdFlags)
+
+	var err error
+	switch args[0] {
+	case "get":
+		err = get(args[1:], logger)
+	case "install":
+		err = install(args[1:], logger)
+	case "sync":
+		err = sync(args[1:], logger)
+	case "list":
+		err = list(args[1:], stdout)
+	case "uninstall":
+		err = uninstall(args[1:], logger)
+	case "cache":
+		err = cache(args[1:], logger)
+	case "help", "-h", "-help", "--help":
+		printUsage(stdout)
+		return 0
+	default:
+		err = errUsage("unknown command: " + args[0])
+	}
+
+	if err == nil {
+		return 0
+	}
+
+	fmt.Fprintln(stderr, err)
+	if _, ok := err.(errUsage); ok {
+		printUsage(stderr)
+		return 2
+	}
+	return 1
+}
+
+type errUsage string
+
+func (e errUsage) Error() string {
+	return string(e)
+}
+
+func printUsage(w io.Writer) {
+	names := []string{}
+	for name := range installers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, usage, strings.Join(names, ", "))
+}
+
+// parse allows the flags to be mixed with the positional args, such as "get <url> -o dir".
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
+
+	rest := []string{}
+	for {
+		err := fs.Parse(args)
+		if err != nil {
+			return nil, errUsage(err.Error())
+		}
+
+		if fs.NArg() == 0 {
+			return rest, nil
+		}
+
+		rest = append(rest, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func get(args []string, logger *log.Logger) error {
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	out := fs.String("o", ".", "the dir or file path to save to")
+	sha := fs.String("sha256", "", "the expected hex sha256 digest of the download")
+
+	urls, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(urls) == 0 {
+		return errUsage("get requires at least one url")
+	}
+
+	f := fetchup.New(urls...).WithSaveTo(*out).WithLogger(logger)
+	f.SHA256 = *sha
+
+	return f.Fetch()
+}
+
+func install(args []string, logger *log.Logger) error {
+	fs := flag.NewFlagSet("install", flag.ContinueOnError)
+	dir := fs.String("dir", "", "the dir to install the executables to, default is $GOBIN or $GOPATH/bin")
+	concurrency := fs.Int("j", 4, "the max number of tools to install at the same time")
+	goos := fs.String("os", "", "the target OS, default is the host OS")
+	arch := fs.String("arch", "", "the target arch, default is the host arch")
+
+	tools, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(tools) == 0 {
+		return errUsage("install requires at least one tool")
+	}
+
+	list := []pkg.Options{}
+	for _, tool := range tools {
+		name, version := tool, ""
+		if i := strings.Index(tool, "@"); i >= 0 {
+			name, version = tool[:i], tool[i+1:]
+		}
+
+		defaults, ok := installers[name]
+		if !ok {
+			return errUsage("unknown tool: " + name)
+		}
+
+		list = append(list, defaults(pkg.Options{
+			Logger:       logger,
+			InstallToDir: *dir,
+			Version:      version,
+			TargetOS:     *goos,
+			TargetArch:   *arch,
+		}))
+	}
+
+	return pkg.InstallAll(context.Background(), list, *concurrency)
+}
+
+func sync(args []string, logger *log.Logger) error {
+	fs := flag.NewFlagSet("sync", flag.ContinueOnError)
+	manifest := fs.String("manifest", "tools.json", "the path of the manifest")
+	lockfile := fs.String("lockfile", "", "the path of the lockfile, default is tools.lock.json next to the manifest")
+	dir := fs.String("dir", "", "the dir to install the executables to, default is $GOBIN or $GOPATH/bin")
+	update := fs.Bool("update", false, "resolve the versions again and update the lockfile")
+	frozen := fs.Bool("frozen", false, "fail if a tool isn't pinned for the current platform in the lockfile")
+
+	tools, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	return pkg.Sync(pkg.SyncOptions{
+		Logger:       logger,
+		Manifest:     *manifest,
+		Lockfile:     *lockfile,
+		InstallToDir: *dir,
+		Update:       *update,
+		Frozen:       *frozen,
+		Tools:        tools,
+	})
+}
+
+func list(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	dir := fs.String("dir", "", "the dir the executables are installed to, default is $GOBIN or $GOPATH/bin")
+
+	_, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	records, err := pkg.List(pkg.Options{InstallToDir: *dir})
+	if err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		fmt.Fprintf(stdout, "%s\t%s\t%s\n", r.Name, r.Version, r.URL)
+	}
+
+	return nil
+}
+
+func uninstall(args []string, logger *log.Logger) error {
+	fs := flag.NewFlagSet("uninstall", flag.ContinueOnError)
+	dir := fs.String("dir", "", "the dir the executables are installed to, default is $GOBIN or $GOPATH/bin")
+
+	tools, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(tools) == 0 {
+		return errUsage("uninstall requires at least one tool")
+	}
+
+	for _, name := range tools {
+		err = pkg.Uninstall(pkg.Options{Logger: logger, InstallToDir: *dir}, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func cache(args []string, logger *log.Logger) error {
+	if len(args) != 1 || args[0] != "clean" {
+		return errUsage("usage: fetchup cache clean")
+	}
+
+	// the default dir of fetchup.New to save the downloads
+	dir := filepath.Join(os.TempDir(), "fetchup")
+
+	err := os.RemoveAll(dir)
+	if err != nil {
+		return err
+	}
+
+	logger.Println("removed " + dir)
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ysmood/got"
+)
+
+func TestGet(t *testing.T) {
+	g := got.T(t)
+
+	buf := bytes.NewBuffer(nil)
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	g.E(tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "a/b.txt", Mode: 0644, Size: 2}))
+	g.E(tw.Write([]byte("ok")))
+	g.E(tw.Close())
+	g.E(gz.Close())
+
+	s := g.Serve()
+	s.Mux.HandleFunc("/t.tar.gz", func(rw http.ResponseWriter, r *http.Request) {
+		g.E(rw.Write(buf.Bytes()))
+	})
+
+	dir := filepath.Join("tmp", g.RandStr(8))
+	stdout, stderr := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+
+	g.Eq(run([]string{"get", s.URL("/t.tar.gz"), "-o", dir}, stdout, stderr), 0)
+	g.Eq(g.Read(filepath.Join(dir, "a", "b.txt")).String(), "ok")
+
+	g.Eq(run([]string{"list", "-dir", filepath.Join(dir, "bin")}, stdout, stderr), 0)
+	g.Eq(stdout.String(), "")
+}
+
+func TestGetFile(t *testing.T) {
+	g := got.T(t)
+
+	s := g.Serve()
+	s.Mux.HandleFunc("/a.txt", func(rw http.ResponseWriter, r *http.Request) {
+		g.E(rw.Write([]byte("ok")))
+	})
+
+	dir := filepath.Join("tmp", g.RandStr(8))
+	g.E(os.MkdirAll(dir, 0755))
+	stdout, stderr := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+
+	// an existing dir gets the file with the name from the url
+	g.Eq(run([]string{"get", s.URL("/a.txt?v=1"), "-o", dir}, stdout, stderr), 0)
+	g.Eq(g.Read(filepath.Join(dir, "a.txt")).String(), "ok")
+
+	g.Eq(run([]string{"get", s.URL("/a.txt"), "-o", filepath.Join(dir, "b.txt")}, stdout, stderr), 0)
+	g.Eq(g.Read(filepath.Join(dir, "b.txt")).String(), "ok")
+}
+
+func TestUsage(t *testing.T) {
+	g := got.T(t)
+
+	stdout, stderr := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+
+	g.Eq(run([]string{}, stdout, stderr), 2)
+	g.Has(stderr.String(), "golangci-lint, migrate")
+
+	stderr.Reset()
+	g.Eq(run([]string{"get"}, stdout, stderr), 2)
+	g.Has(stderr.String(), "get requires at least one url")
+
+	stderr.Reset()
+	g.Eq(run([]string{"install", "unknown"}, stdout, stderr), 2)
+	g.Has(stderr.String(), "unknown tool: unknown")
+
+	g.Eq(run([]string{"uninstall", "-dir", filepath.Join("tmp", g.RandStr(8)), "x"}, stdout, stderr), 1)
+}
@@ -5,89 +5,171 @@ import (
 	"archive/zip"
 	"bytes"
 	"compress/gzip"
+	"context"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
 
 type Response struct {
-	Req            *http.Request
+	// Req is a synthetic GET request that only carries the URL and the context,
+	// it's not the one sent over the wire, a [Source] such as "file" may not use HTTP at all.
+	Req *http.Request
+
 	ResHeader      http.Header
 	ProgressedBody io.Reader
 	Close          func()
+
+	// Name is used to detect the format of the body by its extension, such as ".tar.gz".
+	// If it's empty, the URL will be used.
+	Name string
+
+	digest *verifyReader
 }
 
+// SHA256 returns the hex sha256 digest of the body that has been read.
+func (r *Response) SHA256() string {
+	return r.digest.sum()
+}
+
+// Request opens the URL with the [Source] registered for its scheme in [Fetchup.Sources].
+// A plain local path is opened by the "file" source.
 func (fu *Fetchup) Request(u string) (*Response, error) {
-	req, err := http.NewRequestWithContext(fu.Ctx, http.MethodGet, u, nil)
+	res, parsed, wd, err := fu.open(fu.Ctx, u)
 	if err != nil {
 		return nil, err
 	}
 
-	res, err := fu.HttpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
+	ctx, cancel := wd.ctx, wd.cancel
+
+	digest := newVerifyReader(u, res.Body, fu.SHA256)
+
+	body := wd.watch(fu.RateLimit.Reader(ctx, digest))
+
+	req := (&http.Request{Method: http.MethodGet, URL: parsed, Header: http.Header{}}).WithContext(ctx)
 
 	return &Response{
 		Req:            req,
 		ResHeader:      res.Header,
-		ProgressedBody: newProgress(fu.Ctx, res.Body, int(res.ContentLength), fu.MinReportSpan, fu.Logger),
-		Close:          func() { _ = res.Body.Close() },
+		ProgressedBody: newProgress(fu.Ctx, body, int(res.Size), fu.MinReportSpan, fu.Logger),
+		Close: func() {
+			wd.stop()
+			cancel()
+			_ = res.Body.Close()
+		},
+		Name:   res.Name,
+		digest: digest,
 	}, nil
 }
 
+// open opens u with its [Source], the returned watchdog guards the header and the reading of the body.
+// The watchdog's cancel must be called once the body isn't needed.
+func (fu *Fetchup) open(ctx context.Context, u string) (*SourceResponse, *url.URL, *watchdog, error) {
+	src, parsed, err := fu.source(u)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.WithValue(ctx, targetKey{}, Target{fu.TargetOS, fu.TargetArch}))
+	wd := newWatchdog(ctx, cancel, fu, u)
+
+	stopHeader := wd.header(fu.HeaderTimeout)
+	res, err := src.Open(ctx, fu.client(), parsed)
+	stopHeader()
+	if err != nil {
+		cancel()
+		if e := wd.stalled(); e != nil {
+			return nil, nil, nil, e
+		}
+		return nil, nil, nil, err
+	}
+
+	return res, parsed, wd, nil
+}
+
 func (fu *Fetchup) Download(u string) error {
+	_, err := fu.download(u)
+	return err
+}
+
+// download returns the sha256 digest of the downloaded content.
+func (fu *Fetchup) download(u string) (string, error) {
 	fu.Logger.Println(EventDownload, u)
 
 	res, err := fu.Request(u)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer res.Close()
 
 	r := res.ProgressedBody
+	to := fu.SaveTo
+
+	if res.Name != "" {
+		u = res.Name
+	}
 
 	if strings.HasSuffix(u, ".gz") || res.ResHeader.Get("Content-Encoding") == "gzip" {
 		u = strings.TrimSuffix(u, ".gz")
 		r, err = gzip.NewReader(r)
 		if err != nil {
-			return err
+			return "", err
 		}
 	}
 
 	if strings.HasSuffix(u, ".tar") {
 		err := fu.UnTar(r)
 		if err != nil {
-			return err
+			return "", err
 		}
 	} else if strings.HasSuffix(u, ".zip") {
 		err := fu.UnZip(r)
 		if err != nil {
-			return err
+			return "", err
 		}
 	} else {
-		err = os.MkdirAll(filepath.Dir(fu.SaveTo), 0755)
+		if stat, err := os.Stat(to); err == nil && stat.IsDir() {
+			to = filepath.Join(to, fileName(u))
+		}
+
+		err = os.MkdirAll(filepath.Dir(to), 0755)
 		if err != nil {
-			return err
+			return "", err
 		}
 
-		f, err := os.Create(fu.SaveTo)
+		f, err := os.Create(to)
 		if err != nil {
-			return err
+			return "", err
 		}
 		defer f.Close()
 
 		_, err = io.Copy(f, r)
 		if err != nil {
-			return err
+			return "", err
 		}
 	}
 
-	fu.Logger.Println(EventDownloaded, fu.SaveTo)
+	// Drain the rest, such as the padding of a tar, so that the source can verify the whole content.
+	_, err = io.Copy(io.Discard, res.ProgressedBody)
+	if err != nil {
+		return "", err
+	}
+
+	fu.Logger.Println(EventDownloaded, to)
 
-	return nil
+	return res.SHA256(), nil
+}
+
+// fileName returns the last element of the path of u without the query, such as "a.txt" for "https://a.com/a.txt?v=1".
+func fileName(u string) string {
+	if parsed, err := url.Parse(u); err == nil && parsed.Path != "" {
+		u = parsed.Path
+	}
+	return path.Base(filepath.ToSlash(u))
 }
 
 func (fu *Fetchup) UnZip(r io.Reader) error {
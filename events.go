@@ -7,4 +7,5 @@ const (
 	EventProgress   Event = "Progress:"
 	EventUnzip      Event = "Unzip:"
 	EventDownloaded Event = "Downloaded:"
+	EventStalled    Event = "Stalled:"
 )
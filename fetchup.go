@@ -2,6 +2,7 @@ package fetchup
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -15,10 +16,12 @@ import (
 type Fetchup struct {
 	Ctx context.Context
 
-	// SaveTo is the path to save the file.
+	// SaveTo is the path to save the file, an archive is extracted into it.
+	// If it's an existing dir and the file isn't an archive, the file is saved into it with the name from the URL.
 	SaveTo string
 
 	// URLs is the list of candidates, the fastest one will be used to download the file.
+	// The scheme of each URL should be registered in Sources, a plain local path is also supported.
 	URLs []string
 
 	Logger Logger
@@ -29,7 +32,44 @@ type Fetchup struct {
 
 	MinReportSpan time.Duration
 
+	// HeaderTimeout is the max time to wait for the response header, it includes the time to connect.
+	// Zero means no limit.
+	HeaderTimeout time.Duration
+
+	// IdleTimeout is the max time to wait for the next chunk of the response body.
+	// Zero means no limit.
+	IdleTimeout time.Duration
+
+	// MinSpeed is the min download speed in bytes per second, it's averaged over MinSpeedWindow.
+	// Zero means no limit.
+	MinSpeed int
+
+	// MinSpeedWindow is the sliding window to calculate the speed for MinSpeed.
+	MinSpeedWindow time.Duration
+
+	// RateLimit limits the bandwidth of the requests, it also applies to the probing of [Fetchup.FastestURL].
+	// Share the same limiter between multiple instances to limit their total bandwidth.
+	// Nil means no limit.
+	RateLimit *RateLimiter
+
+	// Auth is the credentials for each host, the key is the host with or without the port, such as "github.com".
+	// Use [LoadNetrc] to load them from the netrc file.
+	Auth map[string]Auth
+
 	HttpClient *http.Client
+
+	// SHA256 is the expected hex sha256 digest of the downloaded content, it's not checked if empty.
+	// A mismatch returns [ErrChecksum].
+	SHA256 string
+
+	// Sources are the handlers for each URL scheme, such as "https" or "s3".
+	// Check [DefaultSources] for the builtin ones.
+	Sources map[string]Source
+
+	// TargetOS and TargetArch are the platform to download for, the sources get them with [TargetFrom].
+	// Default is [runtime.GOOS] and [runtime.GOARCH].
+	TargetOS   string
+	TargetArch string
 }
 
 func New(us ...string) *Fetchup {
@@ -40,37 +80,88 @@ func New(us ...string) *Fetchup {
 		Logger:          log.New(os.Stderr, "", log.LstdFlags),
 		SpeedPacketSize: 64 * 1024,
 		MinReportSpan:   time.Second,
+		MinSpeedWindow:  10 * time.Second,
 		HttpClient: &http.Client{
-			Transport: &DefaultTransport{UA: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/111.0.0.0 Safari/537.36"},
+			Transport: &DefaultTransport{UA: UserAgent()},
 		},
+		Sources: DefaultSources(),
 	}
 }
 
-func (fu *Fetchup) WithContext(ctx context.Context) *Fetchup {
+// clone returns a shallow copy that doesn't share the Sources map with fu.
+func (fu *Fetchup) clone() *Fetchup {
 	n := *fu
-	n.Ctx = ctx
+	if fu.Sources != nil {
+		n.Sources = make(map[string]Source, len(fu.Sources))
+		for k, v := range fu.Sources {
+			n.Sources[k] = v
+		}
+	}
 	return &n
 }
 
+func (fu *Fetchup) WithContext(ctx context.Context) *Fetchup {
+	n := fu.clone()
+	n.Ctx = ctx
+	return n
+}
+
 func (fu *Fetchup) WithSaveTo(to string) *Fetchup {
-	n := *fu
+	n := fu.clone()
 	n.SaveTo = to
-	return &n
+	return n
 }
 
 func (fu *Fetchup) WithLogger(logger Logger) *Fetchup {
-	n := *fu
+	n := fu.clone()
 	n.Logger = logger
-	return &n
+	return n
 }
 
+// Fetch downloads the file from the fastest URL.
+// If the download stalls, it will fail over to the fastest one of the rest URLs.
 func (fu *Fetchup) Fetch() error {
-	u := fu.FastestURL()
-	if u == "" {
-		return &ErrNoURLs{fu.URLs}
-	}
+	_, err := fu.FetchResult()
+	return err
+}
+
+// Result of [Fetchup.FetchResult].
+type Result struct {
+	// URL is the one that the file is downloaded from.
+	URL string
 
-	return fu.Download(u)
+	// SHA256 is the hex sha256 digest of the downloaded content.
+	SHA256 string
+}
+
+// FetchResult is the same as [Fetchup.Fetch], but it also returns the URL used and the digest of the content.
+func (fu *Fetchup) FetchResult() (*Result, error) {
+	urls := fu.URLs
+
+	for {
+		u, err := fu.fastestURL(urls)
+		if err != nil {
+			return nil, err
+		}
+		if u == "" {
+			return nil, &ErrNoURLs{fu.URLs}
+		}
+
+		digest, err := fu.download(u)
+
+		stalled := &ErrStalled{}
+		if errors.As(err, &stalled) && len(urls) > 1 {
+			fu.Logger.Println(EventStalled, stalled)
+			urls = without(urls, u)
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &Result{URL: u, SHA256: digest}, nil
+	}
 }
 
 type ErrNoURLs struct {
@@ -82,12 +173,20 @@ func (e *ErrNoURLs) Error() string {
 }
 
 func (fu *Fetchup) FastestURL() (fastest string) {
+	fastest, _ = fu.fastestURL(fu.URLs)
+	return
+}
+
+// fastestURL returns the URL that passes the probe first.
+// If none of them passes, it returns the [ErrStalled] of the probes if there's any.
+func (fu *Fetchup) fastestURL(urls []string) (fastest string, stalled error) {
 	setURL := sync.Once{}
 	ctx, cancel := context.WithCancel(fu.Ctx)
 	defer cancel()
 
+	lock := sync.Mutex{}
 	wg := sync.WaitGroup{}
-	for _, u := range fu.URLs {
+	for _, u := range urls {
 		u := u
 
 		wg.Add(1)
@@ -95,32 +194,50 @@ func (fu *Fetchup) FastestURL() (fastest string) {
 		go func() {
 			defer wg.Done()
 
-			req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
-			if err != nil {
-				return
-			}
-
-			res, err := fu.HttpClient.Do(req)
-			if err != nil {
-				return
-			}
-			defer func() { _ = res.Body.Close() }()
-
-			if res.StatusCode == http.StatusOK {
-				buf := make([]byte, fu.SpeedPacketSize)
-				_, err = io.ReadFull(res.Body, buf)
-				if err != nil {
-					return
-				}
-
+			err := fu.probe(ctx, u)
+			if err == nil {
 				setURL.Do(func() {
 					fastest = u
 					cancel()
 				})
+				return
+			}
+
+			e := &ErrStalled{}
+			if errors.As(err, &e) {
+				lock.Lock()
+				stalled = e
+				lock.Unlock()
 			}
 		}()
 	}
 	wg.Wait()
 
-	return
+	if fastest != "" {
+		return fastest, nil
+	}
+	return "", stalled
+}
+
+// probe checks if the first packet of u can be downloaded, it's guarded by the same stall limits as the download.
+// A local source, such as a file, has almost zero latency so it usually wins.
+func (fu *Fetchup) probe(ctx context.Context, u string) error {
+	res, _, wd, err := fu.open(ctx, u)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		wd.stop()
+		wd.cancel()
+		_ = res.Body.Close()
+	}()
+
+	buf := make([]byte, fu.SpeedPacketSize)
+	_, err = io.ReadFull(wd.watch(fu.RateLimit.Reader(wd.ctx, res.Body)), buf)
+
+	// the whole file is smaller than the packet
+	if err == nil || err == io.ErrUnexpectedEOF || err == io.EOF {
+		return nil
+	}
+	return err
 }
@@ -1,13 +1,23 @@
 package fetchup_test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/ysmood/fetchup"
 	"github.com/ysmood/got"
@@ -175,3 +185,225 @@ func TestContext(t *testing.T) {
 	fu.Ctx = ctx
 	g.Err(fu.Download(s.URL("/slow/")))
 }
+
+func TestStalled(t *testing.T) {
+	g, s, _ := setup(t)
+
+	check := func(u string, set func(fu *fetchup.Fetchup)) {
+		fu := fetchup.New().WithSaveTo(filepath.Join(getTmpDir(g), "t.out"))
+		fu.Logger = log.New(io.Discard, "", 0)
+		set(fu)
+
+		e := &fetchup.ErrStalled{}
+		g.True(errors.As(fu.Download(u), &e))
+		g.Eq(e.URL, u)
+	}
+
+	check(s.URL("/slow/"), func(fu *fetchup.Fetchup) {
+		fu.HeaderTimeout = 100 * time.Millisecond
+	})
+
+	check(s.URL("/stall/"), func(fu *fetchup.Fetchup) {
+		fu.IdleTimeout = 100 * time.Millisecond
+	})
+
+	check(s.URL("/trickle/"), func(fu *fetchup.Fetchup) {
+		fu.MinSpeed = 1000
+		fu.MinSpeedWindow = 200 * time.Millisecond
+	})
+}
+
+func TestStalledFailover(t *testing.T) {
+	g, s, data := setup(t)
+
+	p := filepath.Join(getTmpDir(g), "t.out")
+
+	fu := fetchup.New(s.URL("/stall/"), s.URL("/file/")).WithSaveTo(p)
+	fu.Logger = log.New(io.Discard, "", 0)
+	fu.SpeedPacketSize = 10
+	fu.IdleTimeout = 100 * time.Millisecond
+	g.E(fu.Fetch())
+
+	g.Eq(g.Read(p).Bytes(), data)
+}
+
+func TestStalledProbe(t *testing.T) {
+	g, s, _ := setup(t)
+
+	fu := fetchup.New(s.URL("/stall/")).WithSaveTo(filepath.Join(getTmpDir(g), "t.out"))
+	fu.Logger = log.New(io.Discard, "", 0)
+	fu.HeaderTimeout = 100 * time.Millisecond
+	fu.IdleTimeout = 100 * time.Millisecond
+
+	ctx := g.Timeout(3 * time.Second)
+	fu = fu.WithContext(ctx)
+
+	e := &fetchup.ErrStalled{}
+	g.True(errors.As(fu.Fetch(), &e))
+	g.Eq(e.URL, s.URL("/stall/"))
+	g.Nil(ctx.Err())
+}
+
+func TestStalledSlowConsumer(t *testing.T) {
+	g, s, _ := setup(t)
+
+	fu := fetchup.New(s.URL("/zip/t.zip")).WithSaveTo(getTmpDir(g))
+	fu.IdleTimeout = 100 * time.Millisecond
+	fu.MinSpeed = 1000
+	fu.MinSpeedWindow = 100 * time.Millisecond
+
+	// the extraction starts after the whole body is received
+	fu.Logger = fetchup.Log(func(msg ...interface{}) {
+		if msg[0] == fetchup.EventUnzip {
+			time.Sleep(300 * time.Millisecond)
+		}
+	})
+
+	g.E(fu.Fetch())
+}
+
+func TestRateLimit(t *testing.T) {
+	g, s, data := setup(t)
+
+	limit := fetchup.NewRateLimiter(80000)
+
+	start := time.Now()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 2; i++ {
+		p := filepath.Join(getTmpDir(g), "t.out")
+
+		fu := fetchup.New(s.URL("/no-content-length/")).WithSaveTo(p)
+		fu.Logger = log.New(io.Discard, "", 0)
+		fu.SpeedPacketSize = 100
+		fu.RateLimit = limit
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.E(fu.Fetch())
+			g.Eq(g.Read(p).Bytes(), data)
+		}()
+	}
+	wg.Wait()
+
+	g.Gt(time.Since(start), 300*time.Millisecond)
+
+	g.Nil(fetchup.NewRateLimiter(0))
+	g.Nil(fetchup.NewRateLimiter(-1))
+}
+
+func TestDefaultTransport(t *testing.T) {
+	g, s, _ := setup(t)
+
+	s.Mux.HandleFunc("/headers/", func(rw http.ResponseWriter, r *http.Request) {
+		g.E(fmt.Fprint(rw, r.Header.Get("User-Agent"), "|", r.Header.Get("X-A"), "|", r.Header.Get("X-B")))
+	})
+
+	via := 0
+
+	p := filepath.Join(getTmpDir(g), "t.out")
+	fu := fetchup.New(s.URL("/headers/")).WithSaveTo(p)
+	fu.Logger = log.New(io.Discard, "", 0)
+	fu.SpeedPacketSize = 1
+	fu.HttpClient.Transport = &fetchup.DefaultTransport{
+		Header: http.Header{"X-A": {"a"}, "X-B": {"b"}},
+		Base: roundTripper(func(r *http.Request) (*http.Response, error) {
+			via++
+			r.Header.Set("X-B", "c")
+			return http.DefaultTransport.RoundTrip(r)
+		}),
+	}
+	g.E(fu.Fetch())
+
+	g.Eq(g.Read(p).String(), fetchup.UserAgent()+"|a|c")
+	g.Eq(fetchup.UserAgent(), "fetchup/dev")
+	g.Eq(via, 2)
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestLocalFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+
+	g, s, _ := setup(t)
+
+	abs, err := filepath.Abs(filepath.FromSlash("fixtures/test.tar"))
+	g.E(err)
+
+	for _, u := range []string{
+		filepath.FromSlash("fixtures/test.tar"),
+		"file://" + abs,
+	} {
+		d := getTmpDir(g)
+
+		fu := fetchup.New(s.URL("/slow/"), "not-exists.tar", u).WithSaveTo(d)
+		fu.Logger = log.New(io.Discard, "", 0)
+		g.Eq(fu.FastestURL(), u)
+		g.E(fu.Fetch())
+
+		g.True(g.PathExists(filepath.Join(d, "test", "a.txt")))
+	}
+}
+
+func TestDataURL(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+
+	g := got.T(t)
+
+	buf := bytes.NewBuffer(nil)
+	gz := gzip.NewWriter(buf)
+	g.E(gz.Write(g.Read(filepath.FromSlash("fixtures/test.tar")).Bytes()))
+	g.E(gz.Close())
+
+	d := getTmpDir(g)
+	fu := fetchup.New("data:application/x-gtar;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())).WithSaveTo(d)
+	fu.Logger = log.New(io.Discard, "", 0)
+	g.E(fu.Fetch())
+	g.Eq(g.Read(filepath.Join(d, "test", "a.txt")).String(), "test test")
+
+	p := filepath.Join(getTmpDir(g), "t.txt")
+	fu = fetchup.New("data:,hello%20world").WithSaveTo(p)
+	fu.Logger = log.New(io.Discard, "", 0)
+	g.E(fu.Fetch())
+	g.Eq(g.Read(p).String(), "hello world")
+}
+
+func TestSHA256(t *testing.T) {
+	g, s, data := setup(t)
+
+	h := sha256.Sum256(data)
+
+	p := filepath.Join(getTmpDir(g), "t.out")
+	fu := fetchup.New().WithSaveTo(p)
+	fu.Logger = log.New(io.Discard, "", 0)
+	fu.SHA256 = hex.EncodeToString(h[:])
+	g.E(fu.Download(s.URL("/no-content-length/")))
+
+	fu.SHA256 = "sha256:" + strings.Repeat("0", 64)
+	e := &fetchup.ErrChecksum{}
+	g.True(errors.As(fu.Download(s.URL("/no-content-length/")), &e))
+	g.Eq(e.Actual, hex.EncodeToString(h[:]))
+}
+
+func TestFetchResult(t *testing.T) {
+	g, s, data := setup(t)
+
+	h := sha256.Sum256(data)
+
+	fu := fetchup.New(s.URL("/no-content-length/")).WithSaveTo(filepath.Join(getTmpDir(g), "t.out"))
+	fu.Logger = log.New(io.Discard, "", 0)
+
+	res, err := fu.FetchResult()
+	g.E(err)
+	g.Eq(res.URL, s.URL("/no-content-length/"))
+	g.Eq(res.SHA256, hex.EncodeToString(h[:]))
+}
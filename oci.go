@@ -0,0 +1,355 @@
+package fetchup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// OCISource downloads a layer of an OCI artifact from a registry, such as "oci://ghcr.io/org/repo:tag",
+// or "oci://ghcr.io/org/repo@sha256:...". The tag is default to "latest".
+// The fragment of the URL selects the layer by its title, such as "oci://ghcr.io/org/repo:tag#tool.tar.gz".
+// The digest of the layer is verified after it's downloaded.
+type OCISource struct {
+	// OS and Arch select the manifest from an image index, default is the [Target] of the download.
+	OS   string
+	Arch string
+
+	// PlainHTTP uses http instead of https to connect the registry.
+	PlainHTTP bool
+
+	// Username and Password are used to get the token, the token is anonymous if they are empty.
+	Username string
+	Password string
+}
+
+var _ Source = (*OCISource)(nil)
+
+const (
+	mediaTypeOCIIndex          = "application/vnd.oci.image.index.v1+json"
+	mediaTypeOCIManifest       = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeDockerList        = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeDockerManifest    = "application/vnd.docker.distribution.manifest.v2+json"
+	annotationTitle            = "org.opencontainers.image.title"
+	defaultOCITag              = "latest"
+	dockerHubRegistry          = "registry-1.docker.io"
+	dockerHubOfficialNamespace = "library/"
+)
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations"`
+	Platform    *struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+	} `json:"platform"`
+}
+
+// ociManifest is either an image index or an image manifest.
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Manifests []ociDescriptor `json:"manifests"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+func (s *OCISource) Open(ctx context.Context, client *http.Client, u *url.URL) (*SourceResponse, error) {
+	repo, ref := parseOCIRef(strings.TrimPrefix(u.Path, "/"))
+	if repo == "" {
+		return nil, fmt.Errorf("invalid OCI URL, expect oci://registry/repo:tag: %s", u)
+	}
+
+	scheme := "https"
+	if s.PlainHTTP {
+		scheme = "http"
+	}
+
+	host := u.Host
+	if host == "docker.io" {
+		host = dockerHubRegistry
+		if !strings.Contains(repo, "/") {
+			repo = dockerHubOfficialNamespace + repo
+		}
+	}
+
+	c := &ociClient{
+		src:    s,
+		client: client,
+		base:   scheme + "://" + host + "/v2/" + repo,
+		repo:   repo,
+	}
+
+	m, err := c.manifest(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(m.Manifests) > 0 {
+		d, err := s.selectManifest(ctx, m.Manifests)
+		if err != nil {
+			return nil, err
+		}
+
+		m, err = c.manifest(ctx, d.Digest)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	layer, err := s.selectLayer(ctx, m.Layers, u.Fragment)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.get(ctx, "/blobs/"+layer.Digest, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	name := layer.Annotations[annotationTitle]
+	if name == "" {
+		name = "layer" + layerExt(layer.MediaType)
+	}
+
+	return &SourceResponse{
+		Body:   newVerifyReader(u.String(), res.Body, layer.Digest),
+		Size:   layer.Size,
+		Header: http.Header{"Content-Type": {layer.MediaType}},
+		Name:   name,
+	}, nil
+}
+
+// parseOCIRef splits "org/repo:tag" or "org/repo@sha256:..." into the repo and the reference.
+func parseOCIRef(s string) (repo, ref string) {
+	if i := strings.Index(s, "@"); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+
+	if i := strings.LastIndex(s, ":"); i > strings.LastIndex(s, "/") {
+		return s[:i], s[i+1:]
+	}
+
+	return s, defaultOCITag
+}
+
+// platform returns the OS and Arch of the source, the empty ones fall back to the [Target] of the ctx.
+func (s *OCISource) platform(ctx context.Context) (string, string) {
+	target := TargetFrom(ctx)
+
+	goos, arch := s.OS, s.Arch
+	if goos == "" {
+		goos = target.OS
+	}
+	if arch == "" {
+		arch = target.Arch
+	}
+	return goos, arch
+}
+
+func (s *OCISource) selectManifest(ctx context.Context, list []ociDescriptor) (*ociDescriptor, error) {
+	goos, arch := s.platform(ctx)
+
+	for i, d := range list {
+		if d.Platform != nil && d.Platform.OS == goos && d.Platform.Architecture == arch {
+			return &list[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("no manifest found for platform %s/%s", goos, arch)
+}
+
+// selectLayer selects the layer by title, if title is empty it selects the only layer,
+// or the layer whose title contains the OS and Arch.
+func (s *OCISource) selectLayer(ctx context.Context, list []ociDescriptor, title string) (*ociDescriptor, error) {
+	if title != "" {
+		for i, d := range list {
+			if d.Annotations[annotationTitle] == title {
+				return &list[i], nil
+			}
+		}
+		return nil, fmt.Errorf("no layer titled %s", title)
+	}
+
+	if len(list) == 1 {
+		return &list[0], nil
+	}
+
+	goos, arch := s.platform(ctx)
+	for i, d := range list {
+		t := strings.ToLower(d.Annotations[annotationTitle])
+		if strings.Contains(t, goos) && strings.Contains(t, arch) {
+			return &list[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("can't select a layer for platform %s/%s from %d layers", goos, arch, len(list))
+}
+
+func layerExt(mediaType string) string {
+	switch {
+	case strings.HasSuffix(mediaType, "tar+gzip"), strings.HasSuffix(mediaType, "tar.gzip"):
+		return ".tar.gz"
+	case strings.HasSuffix(mediaType, "+gzip"):
+		return ".gz"
+	case strings.HasSuffix(mediaType, ".tar"), strings.HasSuffix(mediaType, "+tar"):
+		return ".tar"
+	case strings.HasSuffix(mediaType, "+zip"), strings.HasSuffix(mediaType, "/zip"):
+		return ".zip"
+	}
+	return ""
+}
+
+type ociClient struct {
+	src    *OCISource
+	client *http.Client
+	base   string
+	repo   string
+	token  string
+}
+
+func (c *ociClient) manifest(ctx context.Context, ref string) (*ociManifest, error) {
+	res, err := c.get(ctx, "/manifests/"+ref, []string{
+		mediaTypeOCIIndex, mediaTypeOCIManifest, mediaTypeDockerList, mediaTypeDockerManifest,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	var body io.ReadCloser = res.Body
+	if strings.HasPrefix(ref, "sha256:") {
+		body = newVerifyReader(c.base+"/manifests/"+ref, res.Body, ref)
+	}
+
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &ociManifest{}
+	err = json.Unmarshal(b, m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the manifest: %w", err)
+	}
+
+	return m, nil
+}
+
+// get requests the path under the repo, it fetches the token and retries once if the registry requires auth.
+func (c *ociClient) get(ctx context.Context, path string, accept []string) (*http.Response, error) {
+	fetched := false
+
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+path, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, a := range accept {
+			req.Header.Add("Accept", a)
+		}
+
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+
+		res, err := c.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.StatusCode == http.StatusUnauthorized && !fetched {
+			_ = res.Body.Close()
+
+			c.token, err = c.fetchToken(ctx, res.Header.Get("WWW-Authenticate"))
+			if err != nil {
+				return nil, err
+			}
+			fetched = true
+			continue
+		}
+
+		if res.StatusCode < 200 || res.StatusCode >= 300 {
+			_ = res.Body.Close()
+			return nil, &ErrHTTPStatus{req.URL.String(), res.StatusCode}
+		}
+
+		return res, nil
+	}
+}
+
+var regChallengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// fetchToken gets the token from the realm of the challenge, such as:
+//
+//	Bearer realm="https://ghcr.io/token",service="ghcr.io",scope="repository:org/repo:pull"
+func (c *ociClient) fetchToken(ctx context.Context, challenge string) (string, error) {
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return "", fmt.Errorf("unsupported registry auth challenge: %q", challenge)
+	}
+
+	params := map[string]string{}
+	for _, m := range regChallengeParam.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("invalid realm in registry auth challenge: %q", challenge)
+	}
+
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + c.repo + ":pull"
+	}
+
+	q := realm.Query()
+	q.Set("scope", scope)
+	if params["service"] != "" {
+		q.Set("service", params["service"])
+	}
+	realm.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	if c.src.Username != "" {
+		req.SetBasicAuth(c.src.Username, c.src.Password)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != http.StatusOK {
+		return "", &ErrHTTPStatus{req.URL.String(), res.StatusCode}
+	}
+
+	data := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+
+	err = json.NewDecoder(res.Body).Decode(&data)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse the registry token: %w", err)
+	}
+
+	if data.Token != "" {
+		return data.Token, nil
+	}
+	if data.AccessToken != "" {
+		return data.AccessToken, nil
+	}
+	return "", fmt.Errorf("empty registry token from: %s", realm.Redacted())
+}
@@ -0,0 +1,170 @@
+package fetchup_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/ysmood/fetchup"
+	"github.com/ysmood/got"
+)
+
+func TestOCISource(t *testing.T) {
+	g, s, data := setup(t)
+
+	res, err := http.Get(s.URL("/tar-gz/"))
+	g.E(err)
+	layer, err := io.ReadAll(res.Body)
+	g.E(err)
+	g.E(res.Body.Close())
+
+	digest := func(b []byte) string {
+		h := sha256.Sum256(b)
+		return "sha256:" + hex.EncodeToString(h[:])
+	}
+
+	layerDigest := digest(layer)
+
+	manifest := g.ToJSON(map[string]interface{}{
+		"mediaType": "application/vnd.oci.image.manifest.v1+json",
+		"layers": []interface{}{
+			map[string]interface{}{
+				"mediaType":   "application/vnd.oci.image.layer.v1.tar",
+				"digest":      digest([]byte("other")),
+				"annotations": map[string]string{"org.opencontainers.image.title": "tool_linux_amd64.tar"},
+			},
+			map[string]interface{}{
+				"mediaType":   "application/vnd.oci.image.layer.v1.tar+gzip",
+				"digest":      layerDigest,
+				"size":        len(layer),
+				"annotations": map[string]string{"org.opencontainers.image.title": "tool_linux_arm64.tar.gz"},
+			},
+		},
+	}).Bytes()
+	manifestDigest := digest(manifest)
+
+	index := g.ToJSON(map[string]interface{}{
+		"mediaType": "application/vnd.oci.image.index.v1+json",
+		"manifests": []interface{}{
+			map[string]interface{}{
+				"digest":   digest([]byte("other")),
+				"platform": map[string]string{"os": "windows", "architecture": "arm64"},
+			},
+			map[string]interface{}{
+				"digest":   manifestDigest,
+				"platform": map[string]string{"os": "linux", "architecture": "arm64"},
+			},
+		},
+	}).Bytes()
+
+	s.Mux.HandleFunc("/token", func(rw http.ResponseWriter, r *http.Request) {
+		g.Eq(r.URL.Query().Get("scope"), "repository:org/tool:pull")
+		g.E(json.NewEncoder(rw).Encode(map[string]string{"token": "t"}))
+	})
+
+	s.Mux.HandleFunc("/v2/org/tool/", func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t" {
+			rw.Header().Set("WWW-Authenticate", `Bearer realm="`+s.URL("/token")+`",service="test"`)
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v2/org/tool/manifests/1.0":
+			g.E(rw.Write(index))
+		case "/v2/org/tool/manifests/" + manifestDigest:
+			g.E(rw.Write(manifest))
+		case "/v2/org/tool/blobs/" + layerDigest:
+			g.E(rw.Write(layer))
+		default:
+			g.E(rw.Write([]byte("wrong")))
+		}
+	})
+
+	d := getTmpDir(g)
+
+	fu := fetchup.New("oci://" + s.HostURL.Host + "/org/tool:1.0").WithSaveTo(d)
+	fu.Logger = log.New(io.Discard, "", 0)
+	fu.Sources["oci"] = &fetchup.OCISource{OS: "linux", Arch: "arm64", PlainHTTP: true}
+	g.E(fu.Fetch())
+
+	g.Eq(g.Read(filepath.Join(d, "a", "t.txt")).Bytes(), data)
+
+	fu = fu.WithSaveTo(getTmpDir(g))
+	e := &fetchup.ErrChecksum{}
+	g.True(errors.As(fu.Download("oci://"+s.HostURL.Host+"/org/tool:1.0#tool_linux_amd64.tar"), &e))
+	g.Eq(e.Expected, digest([]byte("other"))[7:])
+}
+
+func TestOCISourceEmptyToken(t *testing.T) {
+	g := got.T(t)
+
+	s := g.Serve()
+
+	count := 0
+	s.Mux.HandleFunc("/token", func(rw http.ResponseWriter, r *http.Request) {
+		g.E(rw.Write([]byte(`{}`)))
+	})
+	s.Mux.HandleFunc("/v2/tool/", func(rw http.ResponseWriter, r *http.Request) {
+		count++
+		rw.Header().Set("WWW-Authenticate", `Bearer realm="`+s.URL("/token")+`"`)
+		rw.WriteHeader(http.StatusUnauthorized)
+	})
+
+	fu := fetchup.New()
+	fu.Logger = log.New(io.Discard, "", 0)
+	fu.Sources["oci"] = &fetchup.OCISource{PlainHTTP: true}
+	g.Has(fu.Download("oci://"+s.HostURL.Host+"/tool").Error(), "empty registry token")
+	g.Eq(count, 1)
+
+	// a token that is rejected is fetched only once for each request
+	s.Mux.HandleFunc("/token-bad", func(rw http.ResponseWriter, r *http.Request) {
+		g.E(rw.Write([]byte(`{"token":"bad"}`)))
+	})
+	s.Mux.HandleFunc("/v2/bad/", func(rw http.ResponseWriter, r *http.Request) {
+		count++
+		rw.Header().Set("WWW-Authenticate", `Bearer realm="`+s.URL("/token-bad")+`"`)
+		rw.WriteHeader(http.StatusUnauthorized)
+	})
+
+	count = 0
+	e := &fetchup.ErrHTTPStatus{}
+	g.True(errors.As(fu.Download("oci://"+s.HostURL.Host+"/bad"), &e))
+	g.Eq(e.StatusCode, http.StatusUnauthorized)
+	g.Eq(count, 2)
+}
+
+func TestOCISourceNoPlatform(t *testing.T) {
+	g := got.T(t)
+
+	s := g.Serve()
+	s.Mux.HandleFunc("/v2/tool/manifests/latest", func(rw http.ResponseWriter, r *http.Request) {
+		g.E(rw.Write([]byte(`{"manifests":[{"platform":{"os":"plan9","architecture":"arm"}}]}`)))
+	})
+
+	fu := fetchup.New()
+	fu.Logger = log.New(io.Discard, "", 0)
+	fu.Sources["oci"] = &fetchup.OCISource{OS: "linux", Arch: "amd64", PlainHTTP: true}
+	g.Has(fu.Download("oci://"+s.HostURL.Host+"/tool").Error(), "no manifest found for platform linux/amd64")
+}
+
+func TestOCISourceTarget(t *testing.T) {
+	g := got.T(t)
+
+	s := g.Serve()
+	s.Mux.HandleFunc("/v2/tool/manifests/latest", func(rw http.ResponseWriter, r *http.Request) {
+		g.E(rw.Write([]byte(`{"manifests":[{"platform":{"os":"plan9","architecture":"arm"}}]}`)))
+	})
+
+	fu := fetchup.New()
+	fu.Logger = log.New(io.Discard, "", 0)
+	fu.Sources["oci"] = &fetchup.OCISource{PlainHTTP: true}
+	fu.TargetOS, fu.TargetArch = "windows", "arm64"
+	g.Has(fu.Download("oci://"+s.HostURL.Host+"/tool").Error(), "no manifest found for platform windows/arm64")
+}
@@ -0,0 +1,191 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ysmood/fetchup"
+)
+
+// ErrInstallAll is returned by [InstallAll] when some tools failed to install.
+type ErrInstallAll struct {
+	// Errors are in the same order as the list passed to [InstallAll].
+	Errors []*ErrInstall
+}
+
+func (e *ErrInstallAll) Error() string {
+	list := []string{}
+	for _, err := range e.Errors {
+		list = append(list, err.Error())
+	}
+
+	return fmt.Sprintf("failed to install %d tools: %s", len(list), strings.Join(list, "; "))
+}
+
+func (e *ErrInstallAll) Unwrap() []error {
+	list := []error{}
+	for _, err := range e.Errors {
+		list = append(list, err)
+	}
+	return list
+}
+
+// ErrInstall is the error of a tool in [ErrInstallAll].
+type ErrInstall struct {
+	// Index of the tool in the list passed to [InstallAll].
+	Index int
+	Name  string
+	Err   error
+}
+
+func (e *ErrInstall) Error() string {
+	return e.Name + ": " + e.Err.Error()
+}
+
+func (e *ErrInstall) Unwrap() error {
+	return e.Err
+}
+
+// InstallAll installs the tools in parallel, at most concurrency tools at the same time.
+// The tools without their own HttpClient share the same one, and the tools without their own CacheDir
+// share a temp one, so that a bundle used by several tools is downloaded only once.
+// The lines of each tool's logger are prefixed with its name, such as "[golangci-lint]",
+// and the progress of all the downloads is reported as one line.
+// All the tools will be tried, the errors are returned as [ErrInstallAll].
+func InstallAll(ctx context.Context, list []Options, concurrency int) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	client := fetchup.New().HttpClient
+
+	cacheDir := ""
+	for _, opts := range list {
+		if opts.CacheDir == "" {
+			dir, err := os.MkdirTemp("", "fetchup-cache-")
+			if err != nil {
+				return err
+			}
+			defer func() { _ = os.RemoveAll(dir) }()
+
+			cacheDir = dir
+			break
+		}
+	}
+
+	p := &allProgress{lock: &sync.Mutex{}, progress: map[int]string{}, names: map[int]string{}}
+	errs := make([]*ErrInstall, len(list))
+	limit := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+
+	for i, opts := range list {
+		if opts.Ctx == nil {
+			opts.Ctx = ctx
+		}
+
+		if opts.HttpClient == nil {
+			opts.HttpClient = client
+		}
+
+		if opts.CacheDir == "" {
+			opts.CacheDir = cacheDir
+		}
+
+		opts = Defaults(opts)
+
+		name := opts.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+
+		opts.Logger = p.logger(i, name, opts.Logger)
+
+		i, opts := i, opts
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			limit <- struct{}{}
+			defer func() { <-limit }()
+
+			_, err := install(opts)
+			if err != nil {
+				errs[i] = &ErrInstall{Index: i, Name: name, Err: err}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	failed := []*ErrInstall{}
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+
+	if len(failed) > 0 {
+		return &ErrInstallAll{failed}
+	}
+
+	return nil
+}
+
+// allProgress combines the progress of the downloads into one line,
+// the lock also prevents the lines of different tools from interleaving.
+type allProgress struct {
+	lock     *sync.Mutex
+	progress map[int]string
+	names    map[int]string
+	last     time.Time
+}
+
+// logger prefixes each line with the name, and merges the progress of the tool into the combined line.
+func (p *allProgress) logger(i int, name string, logger fetchup.Logger) fetchup.Logger {
+	return fetchup.Log(func(msg ...interface{}) {
+		p.lock.Lock()
+		defer p.lock.Unlock()
+
+		if len(msg) > 0 {
+			switch msg[0] {
+			case fetchup.EventProgress:
+				p.names[i] = name
+				p.progress[i] = strings.TrimSpace(fmt.Sprintln(msg[1:]...))
+
+				if time.Since(p.last) < time.Second {
+					return
+				}
+				p.last = time.Now()
+
+				logger.Println(fetchup.EventProgress, p.String())
+				return
+
+			case fetchup.EventDownloaded:
+				delete(p.progress, i)
+			}
+		}
+
+		logger.Println(append([]interface{}{"[" + name + "]"}, msg...)...)
+	})
+}
+
+// String returns the latest progress of the unfinished downloads, such as "a 50%, b 1.024MB".
+func (p *allProgress) String() string {
+	list := []int{}
+	for i := range p.progress {
+		list = append(list, i)
+	}
+	sort.Ints(list)
+
+	out := []string{}
+	for _, i := range list {
+		out = append(out, p.names[i]+" "+p.progress[i])
+	}
+	return strings.Join(out, ", ")
+}
@@ -0,0 +1,99 @@
+package pkg_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ysmood/fetchup"
+	"github.com/ysmood/fetchup/pkg"
+	"github.com/ysmood/got"
+)
+
+func TestInstallAll(t *testing.T) {
+	g := got.T(t)
+
+	var running, max int32
+	hits := map[string]int{}
+	hitsLock := sync.Mutex{}
+
+	s := g.Serve()
+	s.Mux.HandleFunc("/", func(rw http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+
+		time.Sleep(30 * time.Millisecond)
+
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), ".tar.gz")
+
+		hitsLock.Lock()
+		hits[name]++
+		hitsLock.Unlock()
+		if name == "broken" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		// the padding makes the body large enough to report the progress
+		g.E(rw.Write(tarGz(g, map[string]string{name: name, "pad": g.RandStr(1 << 16)})))
+	})
+
+	dir := getTmpDir(g)
+	logger := &bufLogger{}
+
+	list := []pkg.Options{}
+	for _, name := range []string{"a", "b", "broken", "c", "d", "broken"} {
+		list = append(list, pkg.Options{
+			Logger:       fetchup.MultiLogger(logger),
+			InstallToDir: dir,
+			URLs:         pkg.NewTemplates(s.URL("/" + name + ".tar.gz")),
+			BundleBin:    pkg.NewTemplates(name),
+		})
+	}
+
+	// another executable from the same bundle of "a"
+	list = append(list, pkg.Options{
+		Logger:         fetchup.MultiLogger(logger),
+		InstallToDir:   dir,
+		URLs:           pkg.NewTemplates(s.URL("/a.tar.gz")),
+		BundleBin:      pkg.NewTemplates("a"),
+		ExecutableName: pkg.NewTemplate("a2"),
+	})
+
+	err := pkg.InstallAll(context.Background(), list, 2)
+
+	e := &pkg.ErrInstallAll{}
+	g.True(errors.As(err, &e))
+	g.Len(e.Errors, 2)
+	g.Eq(e.Errors[0].Index, 2)
+	g.Eq(e.Errors[1].Index, 5)
+	g.Has(err.Error(), "failed to install 2 tools: broken: ")
+
+	noURLs := &fetchup.ErrNoURLs{}
+	g.True(errors.As(err, &noURLs))
+
+	g.Lte(atomic.LoadInt32(&max), int32(2))
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		g.Eq(g.Read(filepath.Join(dir, name)).String(), name)
+	}
+
+	g.Eq(g.Read(filepath.Join(dir, "a2")).String(), "a")
+	g.Eq(hits["a"], hits["b"])
+
+	g.Has(logger.buf, "[c] Download: "+s.URL("/c.tar.gz"))
+	g.Has(logger.buf, "Progress: ")
+}
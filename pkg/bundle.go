@@ -0,0 +1,152 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// BundleFile maps a file or dir inside the bundle to its installed name.
+type BundleFile struct {
+	// Path is the path inside the bundle, each item will be joined with the OS-specific path separator.
+	Path []Template
+
+	// Name is the name after installation, by default it's the same as the last part of Path.
+	// For share files, it can be a relative path under the [Options.ShareDir].
+	Name Template
+}
+
+// renderedFile is a [BundleFile] whose templates are rendered.
+type renderedFile struct {
+	// src is the relative path inside the bundle
+	src string
+
+	// dst is the full path after installation
+	dst string
+}
+
+func (f BundleFile) render(args map[string]any, dir string) (*renderedFile, error) {
+	if len(f.Path) == 0 {
+		return nil, fmt.Errorf("empty path of bundle file")
+	}
+
+	list := []string{}
+	for _, item := range f.Path {
+		sect, err := item.Render(args)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render bundle path section: %w", err)
+		}
+
+		list = append(list, sect)
+	}
+
+	name := filepath.Base(list[len(list)-1])
+	if !f.Name.IsZero() {
+		var err error
+		name, err = f.Name.Render(args)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render installed name: %w", err)
+		}
+	}
+
+	return &renderedFile{
+		src: filepath.Join(list...),
+		dst: filepath.Join(dir, filepath.FromSlash(name)),
+	}, nil
+}
+
+// bins returns the primary executable first, then the rest of the Bins.
+func (opts Options) bins() ([]*renderedFile, error) {
+	list := opts.Bins
+	if len(opts.BundleBin) > 0 {
+		list = append([]BundleFile{{Path: opts.BundleBin, Name: opts.ExecutableName}}, list...)
+	}
+
+	if len(list) == 0 {
+		return nil, fmt.Errorf("no bundle binary specified, please set BundleBin option")
+	}
+
+	bins := []*renderedFile{}
+	for _, b := range list {
+		r, err := b.render(opts.TemplateArgs, opts.InstallToDir)
+		if err != nil {
+			return nil, err
+		}
+		bins = append(bins, r)
+	}
+
+	return bins, nil
+}
+
+func (opts Options) share() ([]*renderedFile, error) {
+	list := []*renderedFile{}
+	for _, f := range opts.Share {
+		r, err := f.render(opts.TemplateArgs, opts.ShareDir)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, nil
+}
+
+// copyTree copies the file or dir from src to dst, dst will be replaced.
+func copyTree(src, dst string) error {
+	err := os.RemoveAll(dst)
+	if err != nil {
+		return err
+	}
+
+	return filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		to := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(to, 0755)
+
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, to)
+		}
+
+		return copyFile(p, to, info.Mode().Perm())
+	})
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	err := os.MkdirAll(filepath.Dir(dst), 0755)
+	if err != nil {
+		return err
+	}
+
+	from, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = from.Close() }()
+
+	to, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(to, from)
+	if err != nil {
+		_ = to.Close()
+		return err
+	}
+
+	return to.Close()
+}
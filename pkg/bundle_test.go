@@ -0,0 +1,48 @@
+package pkg_test
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/ysmood/fetchup"
+	"github.com/ysmood/fetchup/pkg"
+	"github.com/ysmood/got"
+)
+
+func TestMultipleBins(t *testing.T) {
+	g := got.T(t)
+
+	s := g.Serve()
+	s.Mux.HandleFunc("/protoc.tar.gz", func(rw http.ResponseWriter, r *http.Request) {
+		g.E(rw.Write(tarGz(g, map[string]string{
+			"bin/protoc":                 "protoc",
+			"bin/helper":                 "helper",
+			"include/google/a.proto":     "a",
+			"include/google/sub/b.proto": "b",
+			"readme.txt":                 "readme",
+		})))
+	})
+
+	dir := getTmpDir(g)
+	bin := filepath.Join(dir, "bin")
+
+	g.E(pkg.InstallWithOptions(pkg.Options{
+		Logger:       fetchup.LoggerQuiet,
+		InstallToDir: bin,
+		URLs:         pkg.NewTemplates(s.URL("/protoc.tar.gz")),
+		Bins: []pkg.BundleFile{
+			{Path: pkg.NewTemplates("bin", "protoc")},
+			{Path: pkg.NewTemplates("bin", "helper"), Name: pkg.NewTemplate("protoc-helper")},
+		},
+		Share: []pkg.BundleFile{
+			{Path: pkg.NewTemplates("include")},
+			{Path: pkg.NewTemplates("readme.txt"), Name: pkg.NewTemplate("doc/README")},
+		},
+	}))
+
+	g.Eq(g.Read(filepath.Join(bin, "protoc")).String(), "protoc")
+	g.Eq(g.Read(filepath.Join(bin, "protoc-helper")).String(), "helper")
+	g.Eq(g.Read(filepath.Join(dir, "share", "protoc", "include", "google", "sub", "b.proto")).String(), "b")
+	g.Eq(g.Read(filepath.Join(dir, "share", "protoc", "doc", "README")).String(), "readme")
+}
@@ -0,0 +1,73 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ysmood/fetchup"
+)
+
+// fetch downloads the bundle and returns the dir that it's extracted to, call done when the dir isn't needed.
+// If the CacheDir is set, the bundle is downloaded only once for all the tools that share it,
+// the returned dir is the cached one and must not be modified.
+func fetch(opts Options, f *fetchup.Fetchup) (res *fetchup.Result, dir string, done func(), err error) {
+	if opts.CacheDir == "" {
+		f = f.WithSaveTo(f.SaveTo + "-" + opts.Name)
+
+		res, err = f.FetchResult()
+		if err != nil {
+			_ = os.RemoveAll(f.SaveTo)
+			return nil, "", nil, err
+		}
+
+		return res, f.SaveTo, func() { _ = os.RemoveAll(f.SaveTo) }, nil
+	}
+
+	h := sha256.Sum256([]byte(strings.Join(append([]string{f.SHA256}, f.URLs...), "\n")))
+	dir = filepath.Join(opts.CacheDir, hex.EncodeToString(h[:8]))
+	meta := dir + ".json"
+
+	// The other tools of the same bundle wait until it's downloaded
+	unlock, err := lockFile(opts.Ctx, opts.Logger, dir+".lock")
+	if err != nil {
+		return nil, "", nil, err
+	}
+	defer unlock()
+
+	if b, err := os.ReadFile(meta); err == nil {
+		res = &fetchup.Result{}
+		if json.Unmarshal(b, res) == nil {
+			opts.Logger.Println("use the cached bundle of " + res.URL)
+			return res, dir, func() {}, nil
+		}
+	}
+
+	tmp := dir + ".tmp"
+	_ = os.RemoveAll(tmp)
+
+	res, err = f.WithSaveTo(tmp).FetchResult()
+	if err == nil {
+		_ = os.RemoveAll(dir)
+		err = os.Rename(tmp, dir)
+	}
+	if err != nil {
+		_ = os.RemoveAll(tmp)
+		return nil, "", nil, err
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	err = os.WriteFile(meta, b, 0644)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return res, dir, func() {}, nil
+}
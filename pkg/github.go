@@ -0,0 +1,260 @@
+package pkg
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/ysmood/fetchup"
+)
+
+// GitHubRelease resolves the asset to download from the releases of a GitHub repo.
+type GitHubRelease struct {
+	// Repo is the "owner/name" of the repo, such as "golangci/golangci-lint".
+	Repo string
+
+	// Asset is the glob pattern to match the name of the asset, such as "golangci-lint-*-{{.OS}}-{{.Arch}}{{.BundleExt}}".
+	// It's rendered with the [Options.TemplateArgs] after the version is resolved.
+	// If it's zero value, "*{{.OS}}*{{.Arch}}*{{.BundleExt}}" will be used.
+	Asset Template
+
+	// Checksum is the glob pattern to match the name of the checksum asset, such as "*-checksums.txt".
+	// If it's zero value or no asset matches it, the digest reported by the API will be used if available.
+	Checksum Template
+
+	// Token for the API, default is the env var GITHUB_TOKEN.
+	Token string
+
+	// API is the base URL of the API, default is "https://api.github.com".
+	API string
+}
+
+// GitHubAsset is the resolved result of [GitHubRelease].
+type GitHubAsset struct {
+	Tag     string
+	Version string
+	Name    string
+	URL     string
+
+	// SHA256 is the hex digest of the asset, it's empty if the release doesn't provide it.
+	SHA256 string
+}
+
+type githubRelease struct {
+	TagName    string `json:"tag_name"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+	Assets     []struct {
+		Name   string `json:"name"`
+		URL    string `json:"browser_download_url"`
+		Digest string `json:"digest"`
+	} `json:"assets"`
+}
+
+// Resolve finds the latest release that satisfies the version constraint, and the asset that matches the pattern.
+// The "Version" and "Tag" of the args will be set to the resolved ones.
+func (gh *GitHubRelease) Resolve(ctx context.Context, client *http.Client, version string, args map[string]any) (*GitHubAsset, error) {
+	releases, err := gh.releases(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	constraint, err := ParseConstraint(version)
+	if err != nil {
+		return nil, err
+	}
+
+	// pre-releases are only used when the exact version is required
+	_, exact := ParseSemver(version)
+
+	tags := []string{}
+	for _, r := range releases {
+		if !r.Draft && (!r.Prerelease || exact) {
+			tags = append(tags, r.TagName)
+		}
+	}
+
+	tag, ok := constraint.Latest(tags)
+	if !ok {
+		return nil, fmt.Errorf("no release of %s matches version %q", gh.Repo, version)
+	}
+
+	var release *githubRelease
+	for i := range releases {
+		if releases[i].TagName == tag {
+			release = &releases[i]
+			break
+		}
+	}
+
+	v, _ := ParseSemver(tag)
+
+	args["Version"] = v.String()
+	args["Tag"] = tag
+
+	asset := &GitHubAsset{Tag: tag, Version: v.String()}
+
+	tpl := gh.Asset
+	if tpl.IsZero() {
+		tpl = NewTemplate("*{{.OS}}*{{.Arch}}*{{.BundleExt}}")
+	}
+
+	pattern, err := tpl.Render(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render asset pattern: %w", err)
+	}
+
+	for _, a := range release.Assets {
+		if match(pattern, a.Name) {
+			asset.Name = a.Name
+			asset.URL = a.URL
+			asset.SHA256 = strings.TrimPrefix(a.Digest, "sha256:")
+			break
+		}
+	}
+
+	if asset.URL == "" {
+		return nil, fmt.Errorf("no asset of %s %s matches %q", gh.Repo, tag, pattern)
+	}
+
+	if gh.Checksum.IsZero() {
+		return asset, nil
+	}
+
+	pattern, err = gh.Checksum.Render(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render checksum pattern: %w", err)
+	}
+
+	for _, a := range release.Assets {
+		if match(pattern, a.Name) {
+			sum, err := gh.checksum(ctx, client, a.URL, asset.Name)
+			if err != nil {
+				return nil, err
+			}
+			asset.SHA256 = sum
+			break
+		}
+	}
+
+	return asset, nil
+}
+
+func match(pattern, name string) bool {
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+func (gh *GitHubRelease) api() string {
+	if gh.API == "" {
+		return "https://api.github.com"
+	}
+	return strings.TrimSuffix(gh.API, "/")
+}
+
+func (gh *GitHubRelease) get(ctx context.Context, client *http.Client, u string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(u, gh.api()) {
+		req.Header.Set("Accept", "application/vnd.github+json")
+
+		token := gh.Token
+		if token == "" {
+			token = os.Getenv("GITHUB_TOKEN")
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		_ = res.Body.Close()
+		return nil, &fetchup.ErrHTTPStatus{URL: u, StatusCode: res.StatusCode}
+	}
+
+	return res, nil
+}
+
+var regNextLink = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// releases lists the releases page by page.
+func (gh *GitHubRelease) releases(ctx context.Context, client *http.Client) ([]githubRelease, error) {
+	list := []githubRelease{}
+
+	u := gh.api() + "/repos/" + gh.Repo + "/releases?per_page=100"
+	for page := 0; u != "" && page < 10; page++ {
+		res, err := gh.get(ctx, client, u)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list releases of %s: %w", gh.Repo, err)
+		}
+
+		var releases []githubRelease
+		err = json.NewDecoder(res.Body).Decode(&releases)
+		_ = res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse releases of %s: %w", gh.Repo, err)
+		}
+
+		list = append(list, releases...)
+
+		u = ""
+		if m := regNextLink.FindStringSubmatch(res.Header.Get("Link")); m != nil {
+			u = m[1]
+		}
+	}
+
+	return list, nil
+}
+
+// checksum finds the digest of the name in the checksum file, the format is the output of sha256sum.
+// If the file only has a digest, the digest is returned.
+func (gh *GitHubRelease) checksum(ctx context.Context, client *http.Client, u, name string) (string, error) {
+	res, err := gh.get(ctx, client, u)
+	if err != nil {
+		return "", fmt.Errorf("failed to download checksum file: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	return parseChecksum(io.LimitReader(res.Body, 1024*1024), name)
+}
+
+func parseChecksum(r io.Reader, name string) (string, error) {
+	lines := [][]string{}
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		if fields := strings.Fields(sc.Text()); len(fields) > 0 {
+			lines = append(lines, fields)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return "", err
+	}
+
+	for _, fields := range lines {
+		if len(fields) >= 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+
+	if len(lines) == 1 && len(lines[0]) == 1 {
+		return strings.ToLower(lines[0][0]), nil
+	}
+
+	return "", fmt.Errorf("no checksum found for %s", name)
+}
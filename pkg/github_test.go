@@ -0,0 +1,114 @@
+package pkg_test
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/ysmood/fetchup"
+	"github.com/ysmood/fetchup/pkg"
+	"github.com/ysmood/got"
+)
+
+func serveGitHub(g got.G) (*got.Router, []byte) {
+	s := g.Serve()
+
+	bundle := tarGz(g, map[string]string{"tool-1.2.0/tool": "v1.2.0"})
+
+	asset := func(name string) map[string]string {
+		return map[string]string{"name": name, "browser_download_url": s.URL("/download/", name)}
+	}
+
+	s.Mux.HandleFunc("/repos/org/tool/releases", func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "" {
+			rw.Header().Set("Link", `<`+s.URL("/repos/org/tool/releases?page=2")+`>; rel="next"`)
+			g.E(rw.Write(g.ToJSON([]interface{}{
+				map[string]interface{}{"tag_name": "v2.0.0", "prerelease": true},
+				map[string]interface{}{"tag_name": "v1.2.0", "assets": []interface{}{
+					asset("tool-1.2.0-linux-amd64.tar.gz"),
+					asset("tool-1.2.0-checksums.txt"),
+				}},
+			}).Bytes()))
+			return
+		}
+
+		g.E(rw.Write(g.ToJSON([]interface{}{
+			map[string]interface{}{"tag_name": "v1.1.0"},
+			map[string]interface{}{"tag_name": "nightly"},
+		}).Bytes()))
+	})
+
+	s.Mux.HandleFunc("/download/tool-1.2.0-linux-amd64.tar.gz", func(rw http.ResponseWriter, r *http.Request) {
+		g.E(rw.Write(bundle))
+	})
+
+	s.Mux.HandleFunc("/download/tool-1.2.0-checksums.txt", func(rw http.ResponseWriter, r *http.Request) {
+		g.E(rw.Write([]byte(sha256Hex([]byte("other")) + "  tool-1.2.0-windows-amd64.zip\n" +
+			sha256Hex(bundle) + "  tool-1.2.0-linux-amd64.tar.gz\n")))
+	})
+
+	return s, bundle
+}
+
+func TestGitHubRelease(t *testing.T) {
+	g := got.T(t)
+
+	s, _ := serveGitHub(g)
+
+	dir := getTmpDir(g)
+
+	opts := pkg.Options{
+		Logger:       fetchup.LoggerQuiet,
+		InstallToDir: dir,
+		Version:      "^1",
+		GitHub: &pkg.GitHubRelease{
+			Repo:     "org/tool",
+			API:      s.URL(),
+			Asset:    pkg.NewTemplate("tool-*-linux-amd64{{.BundleExt}}"),
+			Checksum: pkg.NewTemplate("*-checksums.txt"),
+		},
+		BundleBin: pkg.NewTemplates("tool-{{.Version}}", "tool"),
+	}
+
+	g.E(pkg.InstallWithOptions(opts))
+	g.Eq(g.Read(filepath.Join(dir, "tool")).String(), "v1.2.0")
+
+	opts.Version = "2.0.0"
+	g.Has(pkg.InstallWithOptions(opts).Error(), `no asset of org/tool v2.0.0 matches "tool-*-linux-`)
+
+	opts.Version = "latest"
+	opts.SHA256 = sha256Hex([]byte("other"))
+	opts.Exists = func(string) bool { return false }
+	e := &fetchup.ErrChecksum{}
+	g.True(errors.As(pkg.InstallWithOptions(opts), &e))
+
+	// the name is derived after the tag is resolved
+	opts.SHA256 = ""
+	opts.InstallToDir = getTmpDir(g)
+	opts.BundleBin = pkg.NewTemplates(`tool-{{.Tag | trimPrefix "v"}}`, "tool")
+	g.E(pkg.InstallWithOptions(opts))
+
+	list, err := pkg.List(opts)
+	g.E(err)
+	g.Eq(list[0].Name, "tool")
+
+	opts.BundleBin = pkg.NewTemplates("{{.Missing}}")
+	g.Has(pkg.InstallWithOptions(opts).Error(), "failed to derive the tool name, please set Name option")
+}
+
+func TestGitHubReleaseDefaultAsset(t *testing.T) {
+	g := got.T(t)
+
+	s, _ := serveGitHub(g)
+
+	gh := &pkg.GitHubRelease{Repo: "org/tool", API: s.URL()}
+	args := map[string]any{"OS": "linux", "Arch": "amd64", "BundleExt": ".tar.gz"}
+
+	asset, err := gh.Resolve(g.Context(), http.DefaultClient, "latest", args)
+	g.E(err)
+	g.Eq(asset.Name, "tool-1.2.0-linux-amd64.tar.gz")
+
+	_, err = pkg.Template{}.Render(args)
+	g.Eq(err.Error(), "empty template")
+}
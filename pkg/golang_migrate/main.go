@@ -1,16 +1,15 @@
 package golang_migrate
 
 import (
-	"os/exec"
-	"strings"
-
 	"github.com/ysmood/fetchup/pkg"
 )
 
 var DefaultOptions = pkg.Options{
-	Version:   "4.19.0",
-	URLs:      pkg.NewTemplates("https://github.com/golang-migrate/migrate/releases/download/v{{.Version}}/migrate.{{.OS}}-{{.Arch}}{{.BundleExt}}"),
-	BundleBin: pkg.NewTemplates("migrate{{.ExecutableExt}}"),
+	Version:      "4.19.0",
+	URLs:         pkg.NewTemplates("https://github.com/golang-migrate/migrate/releases/download/v{{.Version}}/migrate.{{.OS}}-{{.Arch}}{{.BundleExt}}"),
+	Versions:     &pkg.GitHubRelease{Repo: "golang-migrate/migrate"},
+	VersionProbe: &pkg.VersionProbe{Args: []string{"-version"}},
+	BundleBin:    pkg.NewTemplates("migrate{{.ExecutableExt}}"),
 }
 
 func Install() error {
@@ -18,6 +17,11 @@ func Install() error {
 }
 
 func InstallWithOptions(opts pkg.Options) error {
+	return pkg.InstallWithOptions(Defaults(opts))
+}
+
+// Defaults fills the empty fields with the DefaultOptions, use it to prepare the options for [pkg.InstallAll].
+func Defaults(opts pkg.Options) pkg.Options {
 	if opts.Version == "" {
 		opts.Version = DefaultOptions.Version
 	}
@@ -26,26 +30,17 @@ func InstallWithOptions(opts pkg.Options) error {
 		opts.URLs = DefaultOptions.URLs
 	}
 
-	if opts.BundleBin == nil {
-		opts.BundleBin = DefaultOptions.BundleBin
+	if opts.Versions == nil {
+		opts.Versions = DefaultOptions.Versions
 	}
 
-	opts.Exists = func(path string) bool {
-		return exists(path, opts.Version)
-	}
-
-	return pkg.InstallWithOptions(opts)
-}
-
-func exists(path, version string) bool {
-	if !pkg.ExecExists(path) {
-		return false
+	if opts.BundleBin == nil {
+		opts.BundleBin = DefaultOptions.BundleBin
 	}
 
-	b, err := exec.Command(path, "-version").CombinedOutput()
-	if err != nil {
-		return false
+	if opts.VersionProbe == nil {
+		opts.VersionProbe = DefaultOptions.VersionProbe
 	}
 
-	return strings.TrimSpace(string(b)) == version
+	return opts
 }
@@ -0,0 +1,72 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// link creates or atomically replaces the symlink at path that points to target.
+// On Windows, if the symlink is not allowed, it falls back to a junction for a dir,
+// or a ".cmd" shim for an executable.
+func link(target, path string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	tmp := fmt.Sprintf("%s.%d.tmp", path, time.Now().UnixNano())
+
+	err = os.Symlink(target, tmp)
+	if err != nil {
+		if runtime.GOOS == "windows" {
+			return linkWindows(target, path)
+		}
+		return err
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
+
+func linkWindows(target, path string) error {
+	abs := target
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(filepath.Dir(path), target)
+	}
+
+	stat, err := os.Stat(abs)
+	if err != nil {
+		return err
+	}
+
+	if stat.IsDir() {
+		_ = os.Remove(path)
+		out, err := exec.Command("cmd", "/c", "mklink", "/J", path, abs).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("failed to create junction %s: %w: %s", path, err, out)
+		}
+		return nil
+	}
+
+	shim := strings.TrimSuffix(path, filepath.Ext(path)) + ".cmd"
+	return os.WriteFile(shim, []byte("@\""+abs+"\" %*\r\n"), 0755)
+}
+
+// readLink returns the target of the link, it returns an empty string if path is not a link.
+func readLink(path string) string {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return ""
+	}
+	return target
+}
@@ -0,0 +1,76 @@
+package pkg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ysmood/fetchup"
+)
+
+// lockFile holds the exclusive OS file lock of the path until unlock is called.
+// It waits until the lock is acquired or the ctx is done.
+// The lock works across processes and between the goroutines of the same process.
+func lockFile(ctx context.Context, logger fetchup.Logger, path string) (unlock func(), err error) {
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	for waited := false; ; waited = true {
+		ok, err := tryLock(f)
+		if err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+
+		if ok {
+			return func() {
+				_ = unlockFile(f)
+				_ = f.Close()
+			}, nil
+		}
+
+		if !waited {
+			logger.Println("waiting for the lock " + path)
+		}
+
+		select {
+		case <-ctx.Done():
+			_ = f.Close()
+			return nil, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// lock the install target, so that the installs of the same tool into different dirs don't block each other.
+func (opts Options) lock(dst string) (unlock func(), err error) {
+	return lockFile(opts.Ctx, opts.Logger, opts.lockPath(dst))
+}
+
+// lockState locks the state file for the read-modify-write.
+func (opts Options) lockState() (unlock func(), err error) {
+	return lockFile(opts.Ctx, opts.Logger, opts.lockPath(opts.StateFile))
+}
+
+// lockPath returns the lock file of the path under "<VersionsDir>/locks", such as "tool-1a2b3c4d.lock",
+// so that the lock files don't pollute the dirs of the users, such as the InstallToDir.
+func (opts Options) lockPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	h := sha256.Sum256([]byte(path))
+
+	return filepath.Join(opts.VersionsDir, "locks", filepath.Base(path)+"-"+hex.EncodeToString(h[:4])+".lock")
+}
@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package pkg
+
+import "os"
+
+// The platforms like wasm have no file lock, the install isn't locked.
+
+func tryLock(_ *os.File) (bool, error) {
+	return true, nil
+}
+
+func unlockFile(_ *os.File) error {
+	return nil
+}
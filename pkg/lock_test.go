@@ -0,0 +1,62 @@
+package pkg_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ysmood/fetchup"
+	"github.com/ysmood/fetchup/pkg"
+	"github.com/ysmood/got"
+)
+
+func TestConcurrentInstall(t *testing.T) {
+	g := got.T(t)
+
+	var hits int32
+
+	s := g.Serve()
+	s.Mux.HandleFunc("/tool.tar.gz", func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		time.Sleep(50 * time.Millisecond)
+		g.E(rw.Write(tarGz(g, map[string]string{"tool": "tool"})))
+	})
+
+	dir := filepath.Join(getTmpDir(g), "bin")
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			g.E(pkg.InstallWithOptions(pkg.Options{
+				Logger:       fetchup.LoggerQuiet,
+				InstallToDir: dir,
+				URLs:         pkg.NewTemplates(s.URL("/tool.tar.gz")),
+				BundleBin:    pkg.NewTemplates("tool"),
+			}))
+		}()
+	}
+	wg.Wait()
+
+	g.Eq(g.Read(filepath.Join(dir, "tool")).String(), "tool")
+
+	// the lock files are kept out of the install dir
+	list, err := os.ReadDir(dir)
+	g.E(err)
+	g.Len(list, 2)
+	g.Eq(list[0].Name(), ".fetchup.json")
+	g.Eq(list[1].Name(), "tool")
+
+	list, err = os.ReadDir(filepath.Join(filepath.Dir(dir), "fetchup", "locks"))
+	g.E(err)
+	g.Len(list, 2)
+
+	// one for the probe, one for the download
+	g.Eq(atomic.LoadInt32(&hits), int32(2))
+}
@@ -0,0 +1,372 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/ysmood/fetchup"
+)
+
+// Manifest lists the tools of a project, it's usually the "tools.json" at the root of the repo:
+//
+//	{
+//	  "tools": [
+//	    {
+//	      "name": "golangci-lint",
+//	      "version": "^1.59",
+//	      "github": {"repo": "golangci/golangci-lint", "asset": "*-{{.OS}}-{{.Arch}}{{.BundleExt}}"},
+//	      "bundle_bin": ["golangci-lint-{{.Version}}-{{.OS}}-{{.Arch}}", "golangci-lint{{.ExecutableExt}}"]
+//	    }
+//	  ]
+//	}
+//
+// Only JSON is supported, so that the package has no dependency for the parsing.
+type Manifest struct {
+	Tools []ManifestTool `json:"tools"`
+}
+
+// ManifestTool is the declaration of a tool, the templates are the same as the ones of [Options].
+type ManifestTool struct {
+	Name string `json:"name"`
+
+	// Version can be a constraint, check [ParseConstraint] for the syntax.
+	Version string `json:"version"`
+
+	URLs           []string          `json:"urls,omitempty"`
+	BundleBin      []string          `json:"bundle_bin,omitempty"`
+	ExecutableName string            `json:"executable_name,omitempty"`
+	Bins           []ManifestFile    `json:"bins,omitempty"`
+	Share          []ManifestFile    `json:"share,omitempty"`
+	Tree           bool              `json:"tree,omitempty"`
+	GitHub         *ManifestGitHub   `json:"github,omitempty"`
+	TemplateArgs   map[string]string `json:"template_args,omitempty"`
+
+	// Aliases is the name of the preset aliases, "uname" for [UnameAliases] or "rust" for [RustTripleAliases].
+	Aliases string `json:"aliases,omitempty"`
+}
+
+type ManifestFile struct {
+	Path []string `json:"path"`
+	Name string   `json:"name,omitempty"`
+}
+
+type ManifestGitHub struct {
+	Repo     string `json:"repo"`
+	Asset    string `json:"asset"`
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// LoadManifest reads the manifest from the JSON file.
+func LoadManifest(path string) (*Manifest, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{}
+	err = json.Unmarshal(b, m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+
+	for _, t := range m.Tools {
+		if t.Name == "" {
+			return nil, fmt.Errorf("tool without name in manifest %s", path)
+		}
+	}
+
+	return m, nil
+}
+
+// Options converts the declaration to the options to install the tool.
+func (t *ManifestTool) Options() (Options, error) {
+	opts := Options{
+		Name:    t.Name,
+		Version: t.Version,
+		Tree:    t.Tree,
+	}
+
+	var err error
+
+	if opts.URLs, err = ParseTemplates(t.URLs...); err != nil {
+		return opts, err
+	}
+
+	if opts.BundleBin, err = ParseTemplates(t.BundleBin...); err != nil {
+		return opts, err
+	}
+
+	if t.ExecutableName != "" {
+		if opts.ExecutableName, err = ParseTemplate(t.ExecutableName); err != nil {
+			return opts, err
+		}
+	}
+
+	if opts.Bins, err = parseFiles(t.Bins); err != nil {
+		return opts, err
+	}
+
+	if opts.Share, err = parseFiles(t.Share); err != nil {
+		return opts, err
+	}
+
+	if t.GitHub != nil {
+		opts.GitHub = &GitHubRelease{Repo: t.GitHub.Repo}
+
+		if opts.GitHub.Asset, err = ParseTemplate(t.GitHub.Asset); err != nil {
+			return opts, err
+		}
+
+		if t.GitHub.Checksum != "" {
+			if opts.GitHub.Checksum, err = ParseTemplate(t.GitHub.Checksum); err != nil {
+				return opts, err
+			}
+		}
+	}
+
+	switch t.Aliases {
+	case "":
+	case "uname":
+		opts.Aliases = UnameAliases
+	case "rust":
+		opts.Aliases = RustTripleAliases
+	default:
+		return opts, fmt.Errorf("unknown aliases preset %q", t.Aliases)
+	}
+
+	if len(t.TemplateArgs) > 0 {
+		opts.TemplateArgs = map[string]any{}
+		for k, v := range t.TemplateArgs {
+			opts.TemplateArgs[k] = v
+		}
+	}
+
+	return opts, nil
+}
+
+func parseFiles(list []ManifestFile) ([]BundleFile, error) {
+	files := []BundleFile{}
+	for _, f := range list {
+		path, err := ParseTemplates(f.Path...)
+		if err != nil {
+			return nil, err
+		}
+
+		file := BundleFile{Path: path}
+		if f.Name != "" {
+			if file.Name, err = ParseTemplate(f.Name); err != nil {
+				return nil, err
+			}
+		}
+
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// Lockfile pins the resolved version of each tool in the [Manifest], and the URL and digest for each platform.
+type Lockfile struct {
+	Tools map[string]*LockedTool `json:"tools"`
+}
+
+type LockedTool struct {
+	Version string `json:"version"`
+
+	// Tag is the release tag resolved by the GitHub resolver, it's set as the "Tag" template argument
+	// when the pinned artifact is used, because the resolver is skipped then.
+	Tag string `json:"tag,omitempty"`
+
+	// Platforms is keyed by "<os>/<arch>", such as "linux/amd64".
+	Platforms map[string]*LockedArtifact `json:"platforms"`
+}
+
+type LockedArtifact struct {
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+}
+
+// LoadLockfile reads the lockfile from the JSON file, an empty lockfile is returned if the file doesn't exist.
+func LoadLockfile(path string) (*Lockfile, error) {
+	l := &Lockfile{Tools: map[string]*LockedTool{}}
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(b, l)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile %s: %w", path, err)
+	}
+
+	if l.Tools == nil {
+		l.Tools = map[string]*LockedTool{}
+	}
+
+	return l, nil
+}
+
+// Save writes the lockfile as JSON.
+func (l *Lockfile) Save(path string) error {
+	b, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// SyncOptions for [Sync].
+type SyncOptions struct {
+	Ctx    context.Context
+	Logger fetchup.Logger
+
+	// Manifest is the path of the manifest, default is "tools.json".
+	Manifest string
+
+	// Lockfile is the path of the lockfile, default is "tools.lock.json" in the same dir of the Manifest.
+	Lockfile string
+
+	// InstallToDir is the same as [Options.InstallToDir].
+	InstallToDir string
+
+	// Update resolves the versions again and ignores the pinned ones in the lockfile.
+	Update bool
+
+	// Tools limits the tools to sync by their names, all the tools are synced if it's empty.
+	Tools []string
+
+	// Frozen fails if a tool isn't pinned for the current platform, instead of resolving it and updating
+	// the lockfile. Use it in CI, so that only the artifacts committed in the lockfile can be installed.
+	Frozen bool
+}
+
+// Sync installs the tools in the manifest with the versions, URLs, and digests pinned in the lockfile.
+// The tools that aren't pinned for the current platform are resolved and added to the lockfile,
+// unless [SyncOptions.Frozen] is set. It refuses to install the downloaded artifact if its digest differs
+// from the lockfile.
+func Sync(opts SyncOptions) error {
+	if opts.Frozen && opts.Update {
+		return fmt.Errorf("can't update a frozen lockfile")
+	}
+
+	if opts.Manifest == "" {
+		opts.Manifest = "tools.json"
+	}
+
+	if opts.Lockfile == "" {
+		opts.Lockfile = filepath.Join(filepath.Dir(opts.Manifest), "tools.lock.json")
+	}
+
+	m, err := LoadManifest(opts.Manifest)
+	if err != nil {
+		return err
+	}
+
+	lock, err := LoadLockfile(opts.Lockfile)
+	if err != nil {
+		return err
+	}
+
+	platform := runtime.GOOS + "/" + runtime.GOARCH
+
+	for _, t := range m.Tools {
+		if len(opts.Tools) > 0 && !contains(opts.Tools, t.Name) {
+			continue
+		}
+
+		o, err := t.Options()
+		if err != nil {
+			return fmt.Errorf("invalid manifest entry %s: %w", t.Name, err)
+		}
+
+		o.Ctx = opts.Ctx
+		o.Logger = opts.Logger
+		o.InstallToDir = opts.InstallToDir
+
+		locked := lock.Tools[t.Name]
+
+		if opts.Frozen && (locked == nil || !satisfies(t.Version, locked.Version) || locked.Platforms[platform] == nil) {
+			return fmt.Errorf("%s is not pinned for %s in the lockfile %s", t.Name, platform, opts.Lockfile)
+		}
+
+		if opts.Update || locked == nil || !satisfies(t.Version, locked.Version) {
+			locked = &LockedTool{Platforms: map[string]*LockedArtifact{}}
+		} else {
+			o.Version = locked.Version
+
+			if a := locked.Platforms[platform]; a != nil {
+				o.URLs = []Template{literalTemplate(a.URL)}
+				o.GitHub = nil
+				o.SHA256 = a.SHA256
+
+				if locked.Tag != "" {
+					if o.TemplateArgs == nil {
+						o.TemplateArgs = map[string]any{}
+					}
+					o.TemplateArgs["Tag"] = locked.Tag
+				}
+			}
+		}
+
+		o, err = install(Defaults(o))
+		if err != nil {
+			return fmt.Errorf("failed to install %s: %w", t.Name, err)
+		}
+
+		if locked.Version != o.Version {
+			locked = &LockedTool{Version: o.Version, Platforms: map[string]*LockedArtifact{}}
+		}
+
+		if tag, ok := o.TemplateArgs["Tag"].(string); ok {
+			locked.Tag = tag
+		}
+
+		bins, err := o.bins()
+		if err != nil {
+			return err
+		}
+
+		if r := findRecord(o, bins[0].dst, o.Version); r != nil && r.URL != "" && locked.Platforms[platform] == nil {
+			locked.Platforms[platform] = &LockedArtifact{URL: r.URL, SHA256: r.SHA256}
+		}
+
+		lock.Tools[t.Name] = locked
+	}
+
+	if opts.Frozen {
+		return nil
+	}
+
+	return lock.Save(opts.Lockfile)
+}
+
+// satisfies reports if the locked version is still allowed by the version in the manifest.
+func satisfies(version, locked string) bool {
+	if !IsConstraint(version) {
+		return version == locked || "v"+locked == version
+	}
+
+	c, err := ParseConstraint(version)
+	if err != nil {
+		return false
+	}
+
+	v, ok := ParseSemver(locked)
+	return ok && c.Check(v)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package pkg_test
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/ysmood/fetchup"
+	"github.com/ysmood/fetchup/pkg"
+	"github.com/ysmood/got"
+)
+
+func TestSync(t *testing.T) {
+	g := got.T(t)
+
+	content := "tool 1"
+	s := g.Serve()
+	s.Mux.HandleFunc("/tool-1.0.0.tar.gz", func(rw http.ResponseWriter, r *http.Request) {
+		g.E(rw.Write(tarGz(g, map[string]string{"tool/tool": content})))
+	})
+
+	dir := getTmpDir(g)
+	bin := filepath.Join(dir, "bin")
+	manifest := filepath.Join(dir, "tools.json")
+
+	g.WriteFile(manifest, `{
+		"tools": [{
+			"name": "tool",
+			"version": "1.0.0",
+			"urls": ["`+s.URL("/tool-{{.Version}}.tar.gz")+`"],
+			"bundle_bin": ["tool", "tool"]
+		}]
+	}`)
+
+	opts := pkg.SyncOptions{
+		Logger:       fetchup.LoggerQuiet,
+		Manifest:     manifest,
+		InstallToDir: bin,
+	}
+
+	g.E(pkg.Sync(opts))
+	g.Eq(g.Read(filepath.Join(bin, "tool")).String(), "tool 1")
+
+	lock, err := pkg.LoadLockfile(filepath.Join(dir, "tools.lock.json"))
+	g.E(err)
+	g.Eq(lock.Tools["tool"].Version, "1.0.0")
+
+	artifact := lock.Tools["tool"].Platforms[runtime.GOOS+"/"+runtime.GOARCH]
+	g.Eq(artifact.URL, s.URL("/tool-1.0.0.tar.gz"))
+	g.Len(artifact.SHA256, 64)
+
+	// the artifact is changed on the server
+	content = "tool 1 changed"
+	g.E(os.Remove(filepath.Join(bin, "tool")))
+
+	e := &fetchup.ErrChecksum{}
+	g.True(errors.As(pkg.Sync(opts), &e))
+	g.Eq(e.Expected, artifact.SHA256)
+	g.False(g.PathExists(filepath.Join(bin, "tool")))
+
+	opts.Update = true
+	g.E(pkg.Sync(opts))
+	g.Eq(g.Read(filepath.Join(bin, "tool")).String(), "tool 1 changed")
+
+	lock, err = pkg.LoadLockfile(filepath.Join(dir, "tools.lock.json"))
+	g.E(err)
+	g.Neq(lock.Tools["tool"].Platforms[runtime.GOOS+"/"+runtime.GOARCH].SHA256, artifact.SHA256)
+
+	// a frozen sync only installs the pinned artifacts
+	opts.Update = false
+	opts.Frozen = true
+	g.E(pkg.Sync(opts))
+
+	lock.Tools["tool"].Platforms = map[string]*pkg.LockedArtifact{"plan9/386": artifact}
+	g.E(lock.Save(filepath.Join(dir, "tools.lock.json")))
+	g.Has(pkg.Sync(opts).Error(), "tool is not pinned for "+runtime.GOOS+"/"+runtime.GOARCH)
+
+	lock, err = pkg.LoadLockfile(filepath.Join(dir, "tools.lock.json"))
+	g.E(err)
+	g.Len(lock.Tools["tool"].Platforms, 1)
+}
+
+func TestSyncPinnedURL(t *testing.T) {
+	g := got.T(t)
+
+	bundle := tarGz(g, map[string]string{"tool": "tool"})
+
+	s := g.Serve()
+	s.Mux.HandleFunc("/", func(rw http.ResponseWriter, r *http.Request) {
+		g.Eq(r.URL.Path, "/{{.Version}}.tar.gz")
+		g.E(rw.Write(bundle))
+	})
+
+	dir := getTmpDir(g)
+	manifest := filepath.Join(dir, "tools.json")
+
+	g.WriteFile(manifest, `{"tools": [{"name": "tool", "version": "1.0.0", "bundle_bin": ["tool"]}]}`)
+
+	// the pinned URL is used as it is, it's not rendered again
+	lock := &pkg.Lockfile{Tools: map[string]*pkg.LockedTool{"tool": {
+		Version: "1.0.0",
+		Platforms: map[string]*pkg.LockedArtifact{runtime.GOOS + "/" + runtime.GOARCH: {
+			URL:    s.URL("/{{.Version}}.tar.gz"),
+			SHA256: sha256Hex(bundle),
+		}},
+	}}}
+	g.E(lock.Save(filepath.Join(dir, "tools.lock.json")))
+
+	g.E(pkg.Sync(pkg.SyncOptions{
+		Logger:       fetchup.LoggerQuiet,
+		Manifest:     manifest,
+		InstallToDir: filepath.Join(dir, "bin"),
+		Frozen:       true,
+	}))
+	g.Eq(g.Read(filepath.Join(dir, "bin", "tool")).String(), "tool")
+}
+
+func TestSyncPinnedTag(t *testing.T) {
+	g := got.T(t)
+
+	bundle := tarGz(g, map[string]string{"tool-1.2.0/tool": "tool"})
+
+	s := g.Serve()
+	s.Mux.HandleFunc("/tool.tar.gz", func(rw http.ResponseWriter, r *http.Request) {
+		g.E(rw.Write(bundle))
+	})
+
+	dir := getTmpDir(g)
+	manifest := filepath.Join(dir, "tools.json")
+
+	g.WriteFile(manifest, `{"tools": [{
+		"name": "tool",
+		"version": "^1",
+		"github": {"repo": "org/tool", "asset": "*"},
+		"bundle_bin": ["tool-{{.Tag | trimPrefix \"v\"}}", "tool"]
+	}]}`)
+
+	// the GitHub resolver is skipped for the pinned artifact, the tag comes from the lockfile
+	lock := &pkg.Lockfile{Tools: map[string]*pkg.LockedTool{"tool": {
+		Version: "1.2.0",
+		Tag:     "v1.2.0",
+		Platforms: map[string]*pkg.LockedArtifact{runtime.GOOS + "/" + runtime.GOARCH: {
+			URL:    s.URL("/tool.tar.gz"),
+			SHA256: sha256Hex(bundle),
+		}},
+	}}}
+	g.E(lock.Save(filepath.Join(dir, "tools.lock.json")))
+
+	opts := pkg.SyncOptions{
+		Logger:       fetchup.LoggerQuiet,
+		Manifest:     manifest,
+		InstallToDir: filepath.Join(dir, "bin"),
+	}
+
+	g.E(pkg.Sync(opts))
+	g.Eq(g.Read(filepath.Join(dir, "bin", "tool")).String(), "tool")
+
+	lock, err := pkg.LoadLockfile(filepath.Join(dir, "tools.lock.json"))
+	g.E(err)
+	g.Eq(lock.Tools["tool"].Tag, "v1.2.0")
+}
+
+func TestManifestTool(t *testing.T) {
+	g := got.T(t)
+
+	tool := pkg.ManifestTool{
+		Name:      "lint",
+		Version:   "^1.59",
+		GitHub:    &pkg.ManifestGitHub{Repo: "golangci/golangci-lint", Asset: "*-{{.OS}}-{{.Arch}}{{.BundleExt}}"},
+		BundleBin: []string{"lint-{{.Version}}", "lint"},
+	}
+
+	opts, err := tool.Options()
+	g.E(err)
+	g.Eq(opts.Name, "lint")
+	g.Eq(opts.GitHub.Repo, "golangci/golangci-lint")
+	g.Len(opts.BundleBin, 2)
+
+	tool.URLs = []string{"{{.Version"}
+	_, err = tool.Options()
+	g.Has(err.Error(), `failed to parse template "{{.Version"`)
+}
@@ -1,17 +1,16 @@
 package pkg
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"go/build"
 	"io"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
-	"text/template"
 
 	"github.com/ysmood/fetchup"
 )
@@ -28,11 +27,26 @@ type Options struct {
 	// path is the full path to the executable.
 	// If it returns false, the path will be replaced with the installation.
 	// You can use it to check if the desired version already installed.
+	// If both Exists and VersionProbe are nil, [ExecExists] for the TargetOS will be used.
 	Exists func(path string) bool
 
+	// VersionProbe detects the version of the installed executable when Exists is nil,
+	// the installation is skipped if the version equals the resolved Version, otherwise it's upgraded or downgraded.
+	VersionProbe *VersionProbe
+
+	// Verify is a smoke test of the freshly installed executable, path is the full path of the primary executable.
+	// If it returns an error, the installation fails and the previous executables and share files are restored.
+	// Default is [VersionProbe.Check] of the VersionProbe if it's set.
+	Verify func(ctx context.Context, path, version string) error
+
 	// Version is a shortcut to set Version argument in the TemplateArgs.
+	// It can be a constraint like "latest", "^2.5", or ">=4.18 <5", which is resolved by Versions or GitHub,
+	// check [ParseConstraint] for the syntax.
 	Version string
 
+	// Versions lists the available versions to resolve the Version constraint.
+	Versions VersionLister
+
 	// URLs is a list of URLs to download the bundle from.
 	URLs []Template
 
@@ -44,10 +58,76 @@ type Options struct {
 	// by default it's the same as the last part of BundleBin.
 	ExecutableName Template
 
+	// Bins are the extra executables to install from the same bundle.
+	Bins []BundleFile
+
+	// Share are the non-executable support files or dirs to copy into the ShareDir, such as the includes of protoc.
+	Share []BundleFile
+
+	// ShareDir is the dir to copy the Share files to, default is "<InstallToDir>/../share/<Name>".
+	ShareDir string
+
+	// Tree keeps the whole extracted bundle under "<VersionsDir>/<Name>/<Version>", and links the Bins and Share
+	// files into the InstallToDir and ShareDir, such as JDKs, Node, or Go itself. Exists and VersionProbe are not
+	// used to skip the installation in this mode, switching to an installed version only flips the links.
+	Tree bool
+
+	// Versioned is like Tree, but only keeps the Bins and Share files of each version, so that different versions
+	// can be installed side by side, check [InstalledVersions], [Activate], and [Prune] to manage them.
+	Versioned bool
+
+	// VersionsDir is the root dir to keep the versions of the Tree or Versioned mode,
+	// default is "<InstallToDir>/../fetchup". The lock files of all the modes are kept in its "locks" dir.
+	VersionsDir string
+
+	// StateFile records the installations, default is "<InstallToDir>/.fetchup.json".
+	// Check [List], [Uninstall], and [Verify].
+	StateFile string
+
+	// Name of the tool, by default it's the name of the first executable without extension.
+	Name string
+
+	// TargetOS and TargetArch are the platform to install for, default is [runtime.GOOS] and [runtime.GOARCH].
+	// The template args and the checks of the installed executables follow them, such as to prepare the tools
+	// for a linux/arm64 image on an amd64 host. The VersionProbe isn't used for another platform, because
+	// its executables can't run on the host, the version recorded in the StateFile is compared instead.
+	TargetOS   string
+	TargetArch string
+
+	// Platforms overrides the options for each platform, the key is "<os>/<arch>" or "<os>", such as
+	// "darwin/arm64" or "windows". The "<os>/<arch>" key is preferred. When it's not empty, the platforms that
+	// have no URLs to download get [ErrNoBuild].
+	Platforms map[string]PlatformOptions
+
+	// Aliases maps the OS and Arch to the names used by the upstream, such as "x86_64" for "amd64".
+	// They are available as the OSAlias and ArchAlias arguments, and the osAlias and archAlias template functions.
+	// Check [UnameAliases] and [RustTripleAliases] for the presets.
+	Aliases *Aliases
+
 	// TemplateArgs are the arguments to render the any templates in the options.
 	// It will set some default values like OS, Arch, BundleExt, and ExecutableExt,
 	// check the code of [SetDefaultTemplateArgs] for more details.
 	TemplateArgs map[string]any
+
+	// GitHub resolves the version and the download URL from the releases of a GitHub repo.
+	// When it's set, Version can be "latest" or a constraint like "^2.5", and the resolved asset
+	// will be tried before the URLs.
+	GitHub *GitHubRelease
+
+	// SHA256 is the expected hex sha256 digest of the downloaded bundle.
+	// If it's empty and GitHub is set, the checksum from the release will be used if available.
+	SHA256 string
+
+	// TLS is the options to trust extra CAs and to use client certificates for the downloads.
+	TLS *fetchup.TLSOptions
+
+	// HttpClient for the downloads and the API requests, share it between installations to reuse the connections.
+	// Default is the client of [fetchup.New].
+	HttpClient *http.Client
+
+	// CacheDir keeps the extracted bundles keyed by their URLs, so that the tools from the same bundle
+	// are downloaded only once. It's not cleaned up automatically. [InstallAll] uses a temp dir by default.
+	CacheDir string
 }
 
 func Defaults(opts Options) Options {
@@ -59,8 +139,13 @@ func Defaults(opts Options) Options {
 		opts.InstallToDir = gobin()
 	}
 
-	if opts.Exists == nil {
-		opts.Exists = ExecExists
+	if opts.Exists == nil && opts.VersionProbe == nil {
+		goos, _ := opts.platform()
+		opts.Exists = func(path string) bool { return execExists(path, goos) }
+	}
+
+	if opts.Verify == nil && opts.VersionProbe != nil && !opts.cross() {
+		opts.Verify = opts.VersionProbe.Check
 	}
 
 	if opts.Logger == nil {
@@ -73,79 +158,247 @@ func Defaults(opts Options) Options {
 
 	SetDefaultTemplateArgs(opts)
 
+	opts = applyPlatform(opts)
+
+	// The name may need the args that are resolved later, such as the Tag of the GitHub release,
+	// then it's derived again by the installation.
+	if opts.Name == "" {
+		opts.Name, _ = defaultName(opts)
+	}
+
+	if opts.VersionsDir == "" {
+		opts.VersionsDir = filepath.Join(opts.InstallToDir, "..", "fetchup")
+	}
+
+	if opts.StateFile == "" {
+		opts.StateFile = filepath.Join(opts.InstallToDir, ".fetchup.json")
+	}
+
+	if opts.ShareDir == "" && opts.Name != "" {
+		opts.ShareDir = filepath.Join(opts.InstallToDir, "..", "share", opts.Name)
+	}
+
 	return opts
 }
 
+// defaultName is the name of the first executable without extension.
+func defaultName(opts Options) (string, error) {
+	list := opts.Bins
+	if len(opts.BundleBin) > 0 {
+		list = append([]BundleFile{{Path: opts.BundleBin, Name: opts.ExecutableName}}, list...)
+	}
+
+	if len(list) == 0 {
+		return "", fmt.Errorf("no bundle binary specified, please set BundleBin option")
+	}
+
+	f, err := list[0].render(opts.TemplateArgs, "")
+	if err != nil {
+		return "", err
+	}
+
+	return stripExt(filepath.Base(f.dst)), nil
+}
+
+// named derives the Name and the ShareDir again with the resolved args, if the Name is still empty.
+func (opts Options) named() (Options, error) {
+	if opts.Name != "" {
+		return opts, nil
+	}
+
+	name, err := defaultName(opts)
+	if err != nil {
+		return opts, fmt.Errorf("failed to derive the tool name, please set Name option: %w", err)
+	}
+
+	opts.Name = name
+	if opts.ShareDir == "" {
+		opts.ShareDir = filepath.Join(opts.InstallToDir, "..", "share", opts.Name)
+	}
+
+	return opts, nil
+}
+
 func InstallWithOptions(opts Options) error {
-	opts = Defaults(opts)
+	_, err := install(Defaults(opts))
+	return err
+}
 
-	urls := []string{}
-	for _, urlTpl := range opts.URLs {
-		url, err := urlTpl.Render(opts.TemplateArgs)
+// install returns the options whose Version is resolved, the opts should be the result of [Defaults].
+func install(opts Options) (Options, error) {
+	if err := opts.noBuild(); err != nil {
+		return opts, err
+	}
+
+	f := fetchup.New().WithContext(opts.Ctx).WithLogger(opts.Logger)
+	f.SHA256 = opts.SHA256
+	f.TargetOS, f.TargetArch = opts.platform()
+
+	if opts.HttpClient != nil {
+		f.HttpClient = opts.HttpClient
+	}
+
+	if opts.TLS != nil {
+		var err error
+		f, err = f.WithTLS(opts.TLS)
 		if err != nil {
-			return fmt.Errorf("failed to render URL template: %w", err)
+			return opts, err
 		}
-		urls = append(urls, url)
 	}
 
-	if len(opts.BundleBin) == 0 {
-		return fmt.Errorf("no bundle binary specified, please set BundleBin option")
+	// GitHub resolves the constraint by itself if no other lister is set
+	if opts.GitHub == nil || opts.Versions != nil {
+		version, err := ResolveVersion(opts.Ctx, f.HttpClient, opts.Versions, opts.Version)
+		if err != nil {
+			return opts, err
+		}
+
+		if version != opts.Version {
+			opts.Logger.Println(fmt.Sprintf("resolved version %q to %s", opts.Version, version))
+		}
+
+		opts.Version = version
+		opts.TemplateArgs["Version"] = version
 	}
 
-	bundleBin := []string{}
-	for _, item := range opts.BundleBin {
-		sect, err := item.Render(opts.TemplateArgs)
+	urls := []string{}
+
+	if opts.GitHub != nil {
+		asset, err := opts.GitHub.Resolve(opts.Ctx, f.HttpClient, opts.Version, opts.TemplateArgs)
 		if err != nil {
-			return fmt.Errorf("failed to render bundle binary path section: %w", err)
+			return opts, err
 		}
 
-		bundleBin = append(bundleBin, sect)
+		opts.Version = asset.Version
+		urls = append(urls, asset.URL)
+
+		if f.SHA256 == "" {
+			f.SHA256 = asset.SHA256
+		}
 	}
 
-	exeName := filepath.Base(bundleBin[len(bundleBin)-1])
-	if !opts.ExecutableName.IsZero() {
-		var err error
-		exeName, err = opts.ExecutableName.Render(opts.TemplateArgs)
+	opts, err := opts.named()
+	if err != nil {
+		return opts, err
+	}
+
+	for _, urlTpl := range opts.URLs {
+		url, err := urlTpl.Render(opts.TemplateArgs)
 		if err != nil {
-			return fmt.Errorf("failed to render executable name: %w", err)
+			return opts, fmt.Errorf("failed to render URL template: %w", err)
 		}
+		urls = append(urls, url)
 	}
 
-	installTo := filepath.Join(opts.InstallToDir, exeName)
+	bins, err := opts.bins()
+	if err != nil {
+		return opts, err
+	}
 
-	if opts.Exists(installTo) {
-		opts.Logger.Println("executable already exists at " + installTo + ", skipping installation")
-		return nil
+	share, err := opts.share()
+	if err != nil {
+		return opts, err
 	}
 
-	f := fetchup.New(urls...).WithContext(opts.Ctx).WithLogger(opts.Logger)
-	f = f.WithSaveTo(f.SaveTo + "-" + stripExt(exeName))
+	f.URLs = urls
+
+	if !opts.Tree && !opts.Versioned && skipInstall(opts, bins) {
+		opts.Logger.Println("executable already exists at " + bins[0].dst + ", skipping installation")
+		return opts, nil
+	}
 
-	err := f.Fetch()
+	// Other processes may be installing the same tool, check again after the lock is acquired.
+	unlock, err := opts.lock(bins[0].dst)
 	if err != nil {
-		return err
+		return opts, err
 	}
+	defer unlock()
 
-	defer func() { _ = os.RemoveAll(f.SaveTo) }()
+	if opts.Tree || opts.Versioned {
+		return opts, installVersion(opts, f, bins, share)
+	}
+
+	if skipInstall(opts, bins) {
+		opts.Logger.Println("executable already exists at " + bins[0].dst + ", skipping installation")
+		return opts, nil
+	}
 
-	bin := filepath.Join(f.SaveTo, filepath.Join(bundleBin...))
+	res, dir, done, err := fetch(opts, f)
+	if err != nil {
+		return opts, err
+	}
+	defer done()
 
 	err = os.MkdirAll(opts.InstallToDir, 0755)
 	if err != nil {
-		return fmt.Errorf("failed to create directory %s: %w", opts.InstallToDir, err)
+		return opts, fmt.Errorf("failed to create directory %s: %w", opts.InstallToDir, err)
+	}
+
+	// the installed paths to roll back on failure
+	installed := []string{}
+
+	for _, b := range bins {
+		err = copyBinary(filepath.Join(dir, b.src), b.dst)
+		if err != nil {
+			rollback(opts, installed)
+			return opts, err
+		}
+		installed = append(installed, b.dst)
+	}
+
+	for _, file := range share {
+		err = copyShare(filepath.Join(dir, file.src), file.dst)
+		if err != nil {
+			rollback(opts, installed)
+			return opts, fmt.Errorf("failed to copy share files: %w", err)
+		}
+		installed = append(installed, file.dst)
+	}
+
+	if opts.Verify != nil {
+		err = opts.Verify(opts.Ctx, bins[0].dst, opts.Version)
+		if err != nil {
+			rollback(opts, installed)
+			return opts, err
+		}
 	}
 
-	err = copyAndRemoveBinary(bin, installTo)
+	for _, p := range installed {
+		_ = os.RemoveAll(p + ".bak")
+	}
+
+	return opts, record(opts, res, bins, share)
+}
+
+// record saves the installed files to the state file.
+func record(opts Options, res *fetchup.Result, bins, share []*renderedFile) error {
+	paths := []string{}
+	for _, b := range bins {
+		paths = append(paths, b.dst)
+	}
+	for _, file := range share {
+		paths = append(paths, file.dst)
+	}
+
+	r, err := newRecord(opts, res, bins[0].dst, paths)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	for _, file := range share {
+		if stat, err := os.Stat(file.dst); err == nil && stat.IsDir() {
+			r.Dirs = append(r.Dirs, file.dst)
+		}
+	}
+
+	return saveRecord(opts, r, true)
 }
 
-// copyAndRemoveBinary copies a binary file from src to dst, makes it executable, and removes the source file.
-// Cross-device rename might fail, so we do a copy and remove instead.
-func copyAndRemoveBinary(src, dst string) error {
+// copyBinary copies a binary file from src to dst and makes it executable.
+// Cross-device rename might fail, and src may be shared by other tools in the cache, so we do a copy instead.
+// The binary is synced to a temp file in the same dir then renamed to dst, so dst is never half written.
+// The previous dst is kept as the "<dst>.bak" for [rollback], it's removed after the installation succeeds.
+func copyBinary(src, dst string) error {
 	// Copy the binary to the install location
 	srcFile, err := os.Open(src)
 	if err != nil {
@@ -153,78 +406,112 @@ func copyAndRemoveBinary(src, dst string) error {
 	}
 	defer srcFile.Close()
 
-	dstFile, err := os.Create(dst)
+	dstFile, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".tmp-")
 	if err != nil {
 		return fmt.Errorf("failed to create destination binary: %w", err)
 	}
-	defer dstFile.Close()
+	tmp := dstFile.Name()
+	defer func() { _ = os.Remove(tmp) }()
 
 	_, err = io.Copy(dstFile, srcFile)
+	if err == nil {
+		err = dstFile.Sync()
+	}
+	if err != nil {
+		_ = dstFile.Close()
+		return fmt.Errorf("failed to copy binary: %w", err)
+	}
+
+	err = dstFile.Close()
 	if err != nil {
 		return fmt.Errorf("failed to copy binary: %w", err)
 	}
 
 	// Make the binary executable
-	err = os.Chmod(dst, 0755)
+	err = os.Chmod(tmp, 0755)
 	if err != nil {
 		return fmt.Errorf("failed to make binary executable: %w", err)
 	}
 
-	_ = srcFile.Close()
+	// Keep the previous binary, a running executable can be renamed on all platforms
+	bak := dst + ".bak"
+	_ = os.Remove(bak)
+	if _, err := os.Lstat(dst); err == nil {
+		err = os.Rename(dst, bak)
+		if err != nil {
+			return fmt.Errorf("failed to back up the previous binary: %w", err)
+		}
+	}
 
-	// Remove the source binary
-	err = os.Remove(src)
+	err = os.Rename(tmp, dst)
 	if err != nil {
-		return fmt.Errorf("failed to remove source binary: %w", err)
+		_ = os.Rename(bak, dst)
+		return fmt.Errorf("failed to replace binary: %w", err)
 	}
 
 	return nil
 }
 
-type Template struct {
-	tpl *template.Template
-}
+// copyShare copies the share file or dir from src to dst, the previous dst is kept as the "<dst>.bak" for [rollback].
+func copyShare(src, dst string) error {
+	bak := dst + ".bak"
+	_ = os.RemoveAll(bak)
 
-func NewTemplate(tpl string) Template {
-	return Template{
-		tpl: template.Must(template.New("tpl").Parse(tpl)),
+	_, err := os.Lstat(dst)
+	backed := err == nil
+	if backed {
+		err = os.Rename(dst, bak)
+		if err != nil {
+			return fmt.Errorf("failed to back up the previous share files: %w", err)
+		}
 	}
-}
 
-func NewTemplates(list ...string) []Template {
-	templates := make([]Template, len(list))
-	for i, tpl := range list {
-		templates[i] = NewTemplate(tpl)
+	err = copyTree(src, dst)
+	if err != nil {
+		_ = os.RemoveAll(dst)
+		if backed {
+			_ = os.Rename(bak, dst)
+		}
 	}
-	return templates
-}
-
-func (t Template) IsZero() bool {
-	return t.tpl == nil
+	return err
 }
 
-func (t Template) Render(data map[string]any) (string, error) {
-	buf := bytes.NewBuffer(nil)
-
-	err := t.tpl.Execute(buf, data)
-	if err != nil {
-		return "", fmt.Errorf("failed to execute template: %w", err)
+// rollback restores the installed paths from their ".bak" files, the ones without backup are removed.
+func rollback(opts Options, paths []string) {
+	for _, p := range paths {
+		_ = os.RemoveAll(p)
+
+		bak := p + ".bak"
+		if _, err := os.Lstat(bak); err == nil {
+			_ = os.Rename(bak, p)
+			opts.Logger.Println("restored " + p)
+		} else {
+			opts.Logger.Println("removed " + p)
+		}
 	}
-
-	return buf.String(), nil
 }
 
 func SetDefaultTemplateArgs(opts Options) {
+	goos, arch := opts.platform()
+
 	opts.TemplateArgs["Version"] = opts.Version
-	opts.TemplateArgs["OS"] = runtime.GOOS
-	opts.TemplateArgs["Arch"] = runtime.GOARCH
-	opts.TemplateArgs["BundleExt"] = BundleExt()
-	opts.TemplateArgs["ExecutableExt"] = ExecutableExt()
+	opts.TemplateArgs["OS"] = goos
+	opts.TemplateArgs["Arch"] = arch
+	opts.TemplateArgs["OSAlias"] = opts.Aliases.os(goos)
+	opts.TemplateArgs["ArchAlias"] = opts.Aliases.arch(goos, arch)
+	opts.TemplateArgs["BundleExt"] = BundleExtFor(goos)
+	opts.TemplateArgs["ExecutableExt"] = ExecutableExtFor(goos)
+	opts.TemplateArgs[aliasesKey] = opts.Aliases
 }
 
 // ExecutableExt returns ".exe" for Windows and an empty string for Unix-like systems.
 func ExecutableExt() string {
-	if runtime.GOOS == "windows" {
+	return ExecutableExtFor(runtime.GOOS)
+}
+
+// ExecutableExtFor is the same as [ExecutableExt], but for the goos.
+func ExecutableExtFor(goos string) string {
+	if goos == "windows" {
 		return ".exe"
 	}
 
@@ -233,8 +520,13 @@ func ExecutableExt() string {
 
 // BundleExt returns ".tar.gz" for Unix-like systems and ".zip" for Windows.
 func BundleExt() string {
+	return BundleExtFor(runtime.GOOS)
+}
+
+// BundleExtFor is the same as [BundleExt], but for the goos.
+func BundleExtFor(goos string) string {
 	ext := ".tar.gz"
-	if runtime.GOOS == "windows" {
+	if goos == "windows" {
 		ext = ".zip"
 	}
 
@@ -258,6 +550,11 @@ func gobin() string {
 }
 
 func ExecExists(path string) bool {
+	return execExists(path, runtime.GOOS)
+}
+
+// execExists checks the executable for the goos, it can be different from the host.
+func execExists(path, goos string) bool {
 	stat, err := os.Stat(path)
 	if err != nil {
 		return false
@@ -267,11 +564,16 @@ func ExecExists(path string) bool {
 		return false // It's a directory, not a file
 	}
 
-	if runtime.GOOS == "windows" {
+	if goos == "windows" {
 		ext := strings.ToLower(filepath.Ext(path))
 		return ext == ".exe"
 	}
 
+	// Windows has no executable bit for the Unix-like executables
+	if runtime.GOOS == "windows" {
+		return true
+	}
+
 	// Unix-like: check executable bit
 	return (stat.Mode() & 0111) != 0
 }
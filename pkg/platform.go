@@ -0,0 +1,106 @@
+package pkg
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// PlatformOptions overrides the [Options] for a platform, the empty fields aren't overridden.
+type PlatformOptions struct {
+	URLs           []Template
+	BundleBin      []Template
+	ExecutableName Template
+
+	// TemplateArgs are merged over the default ones, such as {"BundleExt": ".zip"}.
+	TemplateArgs map[string]any
+
+	// Unsupported means the tool has no build for the platform, the install returns [ErrNoBuild].
+	Unsupported bool
+}
+
+// ErrNoBuild is returned when the tool has no build for the platform.
+type ErrNoBuild struct {
+	OS   string
+	Arch string
+}
+
+func (e *ErrNoBuild) Error() string {
+	return fmt.Sprintf("no build for %s/%s", e.OS, e.Arch)
+}
+
+// platform returns the OS and Arch to install for.
+func (opts Options) platform() (string, string) {
+	goos, arch := opts.TargetOS, opts.TargetArch
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+	if arch == "" {
+		arch = runtime.GOARCH
+	}
+	return goos, arch
+}
+
+// platformName returns the target platform as "<os>/<arch>".
+func (opts Options) platformName() string {
+	goos, arch := opts.platform()
+	return goos + "/" + arch
+}
+
+// cross reports if the target platform is not the host, the executables can't run on the host.
+func (opts Options) cross() bool {
+	goos, arch := opts.platform()
+	return goos != runtime.GOOS || arch != runtime.GOARCH
+}
+
+// override returns the override of the platform, the "<os>/<arch>" key is preferred to the "<os>" key.
+func (opts Options) override() (PlatformOptions, bool) {
+	goos, arch := opts.platform()
+
+	if p, ok := opts.Platforms[goos+"/"+arch]; ok {
+		return p, true
+	}
+
+	p, ok := opts.Platforms[goos]
+	return p, ok
+}
+
+// applyPlatform merges the override of the platform over the options.
+func applyPlatform(opts Options) Options {
+	p, ok := opts.override()
+	if !ok {
+		return opts
+	}
+
+	if p.URLs != nil {
+		opts.URLs = p.URLs
+	}
+
+	if p.BundleBin != nil {
+		opts.BundleBin = p.BundleBin
+	}
+
+	if !p.ExecutableName.IsZero() {
+		opts.ExecutableName = p.ExecutableName
+	}
+
+	for k, v := range p.TemplateArgs {
+		opts.TemplateArgs[k] = v
+	}
+
+	return opts
+}
+
+// noBuild returns [ErrNoBuild] if the platform is unsupported, or nothing can be downloaded for it.
+func (opts Options) noBuild() error {
+	if len(opts.Platforms) == 0 {
+		return nil
+	}
+
+	p, _ := opts.override()
+	if p.Unsupported || (len(opts.URLs) == 0 && opts.GitHub == nil) {
+		goos, arch := opts.platform()
+		return &ErrNoBuild{goos, arch}
+	}
+
+	return nil
+}
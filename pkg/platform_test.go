@@ -0,0 +1,109 @@
+package pkg_test
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/ysmood/fetchup"
+	"github.com/ysmood/fetchup/pkg"
+	"github.com/ysmood/got"
+)
+
+func TestPlatforms(t *testing.T) {
+	g := got.T(t)
+
+	s := g.Serve()
+	s.Mux.HandleFunc("/default.tar.gz", func(rw http.ResponseWriter, r *http.Request) {
+		g.E(rw.Write(tarGz(g, map[string]string{"tool": "default"})))
+	})
+	s.Mux.HandleFunc("/special.tar.gz", func(rw http.ResponseWriter, r *http.Request) {
+		g.E(rw.Write(tarGz(g, map[string]string{"dist/special/tool": "special"})))
+	})
+
+	dir := getTmpDir(g)
+	platform := runtime.GOOS + "/" + runtime.GOARCH
+
+	opts := pkg.Options{
+		Logger:       fetchup.LoggerQuiet,
+		InstallToDir: dir,
+		Exists:       func(string) bool { return false },
+		URLs:         pkg.NewTemplates(s.URL("/default.tar.gz")),
+		BundleBin:    pkg.NewTemplates("tool"),
+	}
+
+	o := opts
+	o.Platforms = map[string]pkg.PlatformOptions{
+		runtime.GOOS: {URLs: pkg.NewTemplates(s.URL("/never.tar.gz"))},
+		platform: {
+			URLs:         pkg.NewTemplates(s.URL("/{{.Flavor}}.tar.gz")),
+			BundleBin:    pkg.NewTemplates("dist", "{{.Flavor}}", "tool"),
+			TemplateArgs: map[string]any{"Flavor": "special"},
+		},
+	}
+	g.E(pkg.InstallWithOptions(o))
+	g.Eq(g.Read(filepath.Join(dir, "tool")).String(), "special")
+
+	// other platforms use the defaults
+	o.Platforms = map[string]pkg.PlatformOptions{"plan9": {Unsupported: true}}
+	g.E(pkg.InstallWithOptions(o))
+	g.Eq(g.Read(filepath.Join(dir, "tool")).String(), "default")
+
+	o.Platforms = map[string]pkg.PlatformOptions{runtime.GOOS: {Unsupported: true}}
+	e := &pkg.ErrNoBuild{}
+	g.True(errors.As(pkg.InstallWithOptions(o), &e))
+	g.Eq(e.Error(), "no build for "+platform)
+
+	// only the listed platforms have builds
+	o.URLs = nil
+	o.Platforms = map[string]pkg.PlatformOptions{"plan9/386": {URLs: opts.URLs}}
+	g.Eq(pkg.InstallWithOptions(o).Error(), "no build for "+platform)
+}
+
+func TestTargetPlatform(t *testing.T) {
+	g := got.T(t)
+
+	goos, arch := "linux", "riscv64"
+	if runtime.GOOS == goos && runtime.GOARCH == arch {
+		arch = "arm64"
+	}
+
+	s := g.Serve()
+	s.Mux.HandleFunc("/tool-"+goos+"-"+arch+".tar.gz", func(rw http.ResponseWriter, r *http.Request) {
+		g.E(rw.Write(tarGz(g, map[string]string{"tool": "not runnable on the host"})))
+	})
+
+	dir := getTmpDir(g)
+	logger := &bufLogger{}
+
+	opts := pkg.Options{
+		Logger:       logger,
+		InstallToDir: dir,
+		TargetOS:     goos,
+		TargetArch:   arch,
+		URLs:         pkg.NewTemplates(s.URL("/tool-{{.OS}}-{{.Arch}}{{.BundleExt}}")),
+		BundleBin:    pkg.NewTemplates("tool{{.ExecutableExt}}"),
+		VersionProbe: &pkg.VersionProbe{},
+		Version:      "1.0.0",
+	}
+
+	g.E(pkg.InstallWithOptions(opts))
+	g.Eq(g.Read(filepath.Join(dir, "tool")).String(), "not runnable on the host")
+
+	// the version in the state file is used
+	g.E(pkg.InstallWithOptions(opts))
+	g.Has(logger.buf, "recorded version 1.0.0 for "+goos+"/"+arch+" at "+filepath.Join(dir, "tool"))
+	g.Has(logger.buf, "skipping installation")
+
+	opts.Version = "1.1.0"
+	g.E(pkg.InstallWithOptions(opts))
+	g.Has(logger.buf, "can't detect the version of "+filepath.Join(dir, "tool")+" for another platform, reinstalling")
+
+	args := map[string]any{}
+	pkg.SetDefaultTemplateArgs(pkg.Options{TargetOS: "windows", TargetArch: "arm64", TemplateArgs: args})
+	g.Eq(args["OS"], "windows")
+	g.Eq(args["BundleExt"], ".zip")
+	g.Eq(args["ExecutableExt"], ".exe")
+}
@@ -0,0 +1,144 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// VersionProbe detects the version of an installed executable by running it.
+type VersionProbe struct {
+	// Args to run the executable with, such as []string{"--version"}.
+	Args []string
+
+	// Pattern extracts the version from the combined stdout and stderr, the first submatch is used if it has one.
+	// Default is to match the first semantic version, such as "1.2.3" in "tool version v1.2.3 built at ...".
+	Pattern *regexp.Regexp
+
+	// Timeout to run the executable, default is 10 seconds.
+	Timeout time.Duration
+}
+
+var regDefaultVersion = regexp.MustCompile(`v?(\d+\.\d+\.\d+(?:-[0-9A-Za-z.-]+)?)`)
+
+// Detect runs the executable at path and returns the version it prints.
+func (p *VersionProbe) Detect(ctx context.Context, path string) (string, error) {
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Some tools print the version or banners to stderr
+	out := bytes.NewBuffer(nil)
+	cmd := exec.CommandContext(ctx, path, p.Args...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("failed to run %s: %w: %s", path, err, out.String())
+	}
+
+	pattern := p.Pattern
+	if pattern == nil {
+		pattern = regDefaultVersion
+	}
+
+	m := pattern.FindStringSubmatch(out.String())
+	if m == nil {
+		return "", fmt.Errorf("no version found in the output of %s: %q", path, out.String())
+	}
+
+	if len(m) > 1 {
+		return m[1], nil
+	}
+	return m[0], nil
+}
+
+// skipInstall reports if the installation can be skipped. All the executables must exist.
+// If Exists is set, it's used to check the primary executable,
+// otherwise the VersionProbe is used to compare the installed version with the desired one.
+func skipInstall(opts Options, bins []*renderedFile) bool {
+	goos, _ := opts.platform()
+
+	for _, b := range bins[1:] {
+		if !execExists(b.dst, goos) {
+			return false
+		}
+	}
+
+	path := bins[0].dst
+
+	if opts.Exists != nil {
+		return opts.Exists(path)
+	}
+
+	if !execExists(path, goos) {
+		return false
+	}
+
+	// The executable can't run on the host, trust the version in the state file if the file is unchanged.
+	if opts.cross() {
+		if r := installedRecord(opts, path); r != nil && sameVersion(r.Version, opts.Version) {
+			opts.Logger.Println(fmt.Sprintf("recorded version %s for %s at %s", r.Version, r.Platform, path))
+			return true
+		}
+
+		opts.Logger.Println("can't detect the version of " + path + " for another platform, reinstalling")
+		return false
+	}
+
+	installed, err := opts.VersionProbe.Detect(opts.Ctx, path)
+	if err != nil {
+		opts.Logger.Println(fmt.Sprintf("failed to detect the version of %s, reinstalling: %v", path, err))
+		return false
+	}
+
+	opts.Logger.Println(fmt.Sprintf("detected version %s at %s", installed, path))
+
+	if sameVersion(installed, opts.Version) {
+		return true
+	}
+
+	action := "replacing"
+	a, okA := ParseSemver(installed)
+	b, okB := ParseSemver(opts.Version)
+	if okA && okB {
+		if a.Compare(b) < 0 {
+			action = "upgrading"
+		} else {
+			action = "downgrading"
+		}
+	}
+
+	opts.Logger.Println(fmt.Sprintf("%s %s from %s to %s", action, path, installed, opts.Version))
+
+	return false
+}
+
+// Check runs the executable at path and reports an error if it doesn't print the version.
+// It only checks if the executable runs when the version is empty. It can be used as the [Options.Verify].
+func (p *VersionProbe) Check(ctx context.Context, path, version string) error {
+	installed, err := p.Detect(ctx, path)
+	if err != nil {
+		return fmt.Errorf("failed to check the installed executable: %w", err)
+	}
+
+	if version != "" && !sameVersion(installed, version) {
+		return fmt.Errorf("the installed %s reports version %s, expected %s", path, installed, version)
+	}
+
+	return nil
+}
+
+// sameVersion reports if a and b are the same version, the "v" prefix is ignored.
+func sameVersion(a, b string) bool {
+	return strings.TrimPrefix(a, "v") == strings.TrimPrefix(b, "v")
+}
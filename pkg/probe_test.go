@@ -0,0 +1,185 @@
+package pkg_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/ysmood/fetchup"
+	"github.com/ysmood/fetchup/pkg"
+	"github.com/ysmood/got"
+)
+
+func TestVersionProbe(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+
+	g := got.T(t)
+
+	s := serveTool(g, nil)
+
+	dir := getTmpDir(g)
+
+	install := func(version string) string {
+		logger := &bufLogger{}
+		g.E(pkg.InstallWithOptions(pkg.Options{
+			Logger:       logger,
+			InstallToDir: dir,
+			Version:      version,
+			URLs:         pkg.NewTemplates(s.URL("/tool/{{.Version}}.tar.gz")),
+			BundleBin:    pkg.NewTemplates("tool"),
+			VersionProbe: &pkg.VersionProbe{Args: []string{"--version"}},
+		}))
+		return logger.buf
+	}
+
+	g.Has(install("1.2.0"), "Downloaded:")
+
+	out := install("1.2.0")
+	g.Has(out, "detected version 1.2.0 at "+filepath.Join(dir, "tool"))
+	g.Has(out, "skipping installation")
+
+	// the "v" prefix doesn't matter
+	g.Has(install("v1.2.0"), "skipping installation")
+
+	g.Has(install("1.10.0"), "upgrading "+filepath.Join(dir, "tool")+" from 1.2.0 to 1.10.0")
+	g.Has(install("1.3.0"), "downgrading")
+
+	v, err := (&pkg.VersionProbe{}).Detect(g.Context(), filepath.Join(dir, "tool"))
+	g.E(err)
+	g.Eq(v, "1.3.0")
+}
+
+func TestRollback(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+
+	g := got.T(t)
+
+	// the asset of 2.0.0 is replaced with a wrong build
+	s := serveTool(g, map[string]string{"2.0.0": "1.9.0"})
+
+	dir := getTmpDir(g)
+	tool := filepath.Join(dir, "tool")
+
+	install := func(version string) error {
+		return pkg.InstallWithOptions(pkg.Options{
+			Logger:       fetchup.LoggerQuiet,
+			InstallToDir: dir,
+			Version:      version,
+			URLs:         pkg.NewTemplates(s.URL("/tool/{{.Version}}.tar.gz")),
+			BundleBin:    pkg.NewTemplates("tool"),
+			VersionProbe: &pkg.VersionProbe{},
+		})
+	}
+
+	g.E(install("1.0.0"))
+	g.False(g.PathExists(tool + ".bak"))
+
+	g.Eq(install("2.0.0").Error(), "the installed "+tool+" reports version 1.9.0, expected 2.0.0")
+	g.Eq(g.Read(tool).String(), versionScript("1.0.0"))
+	g.False(g.PathExists(tool + ".bak"))
+
+	g.E(install("3.0.0"))
+	g.Eq(g.Read(tool).String(), versionScript("3.0.0"))
+	g.False(g.PathExists(tool + ".bak"))
+
+	// the first binary isn't in the bundle
+	err := pkg.InstallWithOptions(pkg.Options{
+		Logger:       fetchup.LoggerQuiet,
+		InstallToDir: dir,
+		Version:      "4.0.0",
+		URLs:         pkg.NewTemplates(s.URL("/tool/{{.Version}}.tar.gz")),
+		BundleBin:    pkg.NewTemplates("wrong"),
+		Exists:       func(string) bool { return false },
+	})
+	g.Has(err.Error(), "failed to open source binary")
+	g.Eq(g.Read(tool).String(), versionScript("3.0.0"))
+}
+
+func TestRollbackShare(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+
+	g := got.T(t)
+
+	s := serveTool(g, nil, "lib/a.txt")
+
+	dir := getTmpDir(g)
+	bin := filepath.Join(dir, "bin")
+	lib := filepath.Join(dir, "share", "tool", "lib", "a.txt")
+
+	install := func(version string, verify error) error {
+		return pkg.InstallWithOptions(pkg.Options{
+			Logger:       fetchup.LoggerQuiet,
+			InstallToDir: bin,
+			Version:      version,
+			URLs:         pkg.NewTemplates(s.URL("/tool/{{.Version}}.tar.gz")),
+			BundleBin:    pkg.NewTemplates("tool"),
+			Share:        []pkg.BundleFile{{Path: pkg.NewTemplates("lib")}},
+			Exists:       func(string) bool { return false },
+			Verify:       func(context.Context, string, string) error { return verify },
+		})
+	}
+
+	g.E(install("1.0.0", nil))
+	g.Eq(g.Read(lib).String(), "1.0.0")
+
+	g.Eq(install("2.0.0", errors.New("broken")).Error(), "broken")
+	g.Eq(g.Read(lib).String(), "1.0.0")
+	g.Eq(g.Read(filepath.Join(bin, "tool")).String(), versionScript("1.0.0"))
+	g.False(g.PathExists(filepath.Dir(lib) + ".bak"))
+}
+
+func TestVerify(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+
+	g := got.T(t)
+
+	s := serveTool(g, map[string]string{"2.0.0": "1.9.0"})
+
+	dir := getTmpDir(g)
+	tool := filepath.Join(dir, "tool")
+
+	opts := pkg.Options{
+		Logger:       fetchup.LoggerQuiet,
+		InstallToDir: dir,
+		URLs:         pkg.NewTemplates(s.URL("/tool/{{.Version}}.tar.gz")),
+		BundleBin:    pkg.NewTemplates("tool"),
+	}
+
+	// a custom smoke test
+	o := opts
+	o.Version = "1.0.0"
+	o.Verify = func(ctx context.Context, path, version string) error {
+		g.Eq(path, tool)
+		g.Eq(version, "1.0.0")
+		return errors.New("broken")
+	}
+	g.Eq(pkg.InstallWithOptions(o).Error(), "broken")
+	g.False(g.PathExists(tool))
+
+	// the versioned install is verified before it's activated
+	opts.Versioned = true
+	opts.VersionProbe = &pkg.VersionProbe{}
+
+	o = opts
+	o.Version = "1.0.0"
+	g.E(pkg.InstallWithOptions(o))
+
+	o.Version = "2.0.0"
+	g.Has(pkg.InstallWithOptions(o).Error(), "reports version 1.9.0, expected 2.0.0")
+	g.Eq(pkg.ActiveVersion(opts), "1.0.0")
+	g.Eq(g.Read(tool).String(), versionScript("1.0.0"))
+
+	versions, err := pkg.InstalledVersions(opts)
+	g.E(err)
+	g.Eq(versions, []string{"1.0.0"})
+}
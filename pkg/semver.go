@@ -0,0 +1,286 @@
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Semver is a semantic version, such as "1.2.3" or "v1.2.3-rc.1".
+type Semver struct {
+	Major int
+	Minor int
+	Patch int
+	Pre   string
+}
+
+var regSemver = regexp.MustCompile(`^v?(\d+)(?:\.(\d+|x|X|\*))?(?:\.(\d+|x|X|\*))?(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+
+// ParseSemver parses a full version, such as "1.2.3", the "v" prefix and build metadata are allowed.
+func ParseSemver(s string) (Semver, bool) {
+	v, parts, ok := parsePartial(s)
+	return v, ok && parts == 3
+}
+
+// parsePartial parses versions like "1", "1.2", or "1.2.x", parts is the number of the specified numbers.
+func parsePartial(s string) (v Semver, parts int, ok bool) {
+	m := regSemver.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return Semver{}, 0, false
+	}
+
+	nums := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, n := range m[1:4] {
+		if n == "" || n == "x" || n == "X" || n == "*" {
+			break
+		}
+		*nums[i], _ = strconv.Atoi(n)
+		parts++
+	}
+
+	v.Pre = m[4]
+	if v.Pre != "" && parts < 3 {
+		return Semver{}, 0, false
+	}
+
+	return v, parts, true
+}
+
+func (v Semver) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// Compare returns -1, 0, or 1 if v is less than, equal to, or greater than o.
+func (v Semver) Compare(o Semver) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		} else if d > 0 {
+			return 1
+		}
+	}
+
+	switch {
+	case v.Pre == o.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case o.Pre == "":
+		return -1
+	}
+
+	return comparePre(v.Pre, o.Pre)
+}
+
+func comparePre(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				return sign(an - bn)
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+
+	return sign(len(as) - len(bs))
+}
+
+func sign(n int) int {
+	if n < 0 {
+		return -1
+	} else if n > 0 {
+		return 1
+	}
+	return 0
+}
+
+// Constraint is a version range, such as "^2.5", "~1.2.3", ">=4.18 <5", "1.x || 2.x", or "latest".
+// Pre-releases only match the comparators that have a pre-release.
+type Constraint struct {
+	raw    string
+	groups [][]comparator
+}
+
+type comparator struct {
+	op string
+	v  Semver
+}
+
+var regComparator = regexp.MustCompile(`^(>=|<=|>|<|=|\^|~)?\s*(.+)$`)
+
+// ParseConstraint parses the constraint, an empty string, "latest", or "*" matches any stable version.
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{raw: s}
+
+	for _, group := range strings.Split(s, "||") {
+		// join the operators with their versions, such as ">= 1.2" to ">=1.2"
+		fields := strings.Fields(strings.ReplaceAll(group, ",", " "))
+		for i := 0; i < len(fields)-1; i++ {
+			if strings.Trim(fields[i], "<>=^~") == "" {
+				fields[i+1] = fields[i] + fields[i+1]
+				fields[i] = ""
+			}
+		}
+
+		list := []comparator{}
+		for _, f := range fields {
+			if f == "" || f == "*" || f == "latest" {
+				continue
+			}
+
+			cs, err := parseComparator(f)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, cs...)
+		}
+
+		c.groups = append(c.groups, list)
+	}
+
+	return c, nil
+}
+
+// parseComparator expands the shortcuts into the basic comparators.
+func parseComparator(s string) ([]comparator, error) {
+	m := regComparator.FindStringSubmatch(s)
+	if m == nil {
+		return nil, fmt.Errorf("invalid version constraint: %q", s)
+	}
+
+	op := m[1]
+	v, parts, ok := parsePartial(m[2])
+	if !ok {
+		return nil, fmt.Errorf("invalid version in constraint: %q", s)
+	}
+
+	// the version right after the range of v, such as 1.3.0 for 1.2
+	next := func(parts int) Semver {
+		switch parts {
+		case 1:
+			return Semver{Major: v.Major + 1}
+		case 2:
+			return Semver{Major: v.Major, Minor: v.Minor + 1}
+		}
+		return Semver{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+	}
+
+	switch op {
+	case "", "=":
+		if parts == 3 {
+			return []comparator{{"=", v}}, nil
+		}
+		return []comparator{{">=", v}, {"<", next(parts)}}, nil
+
+	case "^":
+		switch {
+		case v.Major > 0 || parts == 1:
+			return []comparator{{">=", v}, {"<", next(1)}}, nil
+		case v.Minor > 0 || parts == 2:
+			return []comparator{{">=", v}, {"<", next(2)}}, nil
+		}
+		return []comparator{{">=", v}, {"<", next(3)}}, nil
+
+	case "~":
+		if parts == 1 {
+			return []comparator{{">=", v}, {"<", next(1)}}, nil
+		}
+		return []comparator{{">=", v}, {"<", next(2)}}, nil
+
+	case ">":
+		if parts < 3 {
+			return []comparator{{">=", next(parts)}}, nil
+		}
+
+	case "<=":
+		if parts < 3 {
+			return []comparator{{"<", next(parts)}}, nil
+		}
+	}
+
+	return []comparator{{op, v}}, nil
+}
+
+func (c *Constraint) String() string {
+	return c.raw
+}
+
+// Check reports if v satisfies the constraint.
+func (c *Constraint) Check(v Semver) bool {
+	for _, group := range c.groups {
+		if checkGroup(group, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkGroup(group []comparator, v Semver) bool {
+	allowPre := false
+
+	for _, cmp := range group {
+		r := v.Compare(cmp.v)
+
+		ok := false
+		switch cmp.op {
+		case "=":
+			ok = r == 0
+		case ">":
+			ok = r > 0
+		case ">=":
+			ok = r >= 0
+		case "<":
+			ok = r < 0
+		case "<=":
+			ok = r <= 0
+		}
+
+		if !ok {
+			return false
+		}
+
+		if cmp.v.Pre != "" && cmp.v.Major == v.Major && cmp.v.Minor == v.Minor && cmp.v.Patch == v.Patch {
+			allowPre = true
+		}
+	}
+
+	return v.Pre == "" || allowPre
+}
+
+// Latest returns the greatest version in the list that satisfies the constraint, versions that are not
+// semantic versions are ignored. It returns false if nothing matches.
+func (c *Constraint) Latest(list []string) (string, bool) {
+	found := ""
+	var latest Semver
+
+	for _, s := range list {
+		v, ok := ParseSemver(s)
+		if !ok || !c.Check(v) {
+			continue
+		}
+
+		if found == "" || v.Compare(latest) > 0 {
+			found, latest = s, v
+		}
+	}
+
+	return found, found != ""
+}
@@ -0,0 +1,44 @@
+package pkg_test
+
+import (
+	"testing"
+
+	"github.com/ysmood/fetchup/pkg"
+	"github.com/ysmood/got"
+)
+
+func TestConstraint(t *testing.T) {
+	g := got.T(t)
+
+	list := []string{"v1.0.0", "1.2.3", "1.2.10", "v1.3.0-rc.1", "2.5.0", "2.6.1", "4.18.2", "4.19.0", "5.0.0", "nightly"}
+
+	for constraint, expected := range map[string]string{
+		"":             "5.0.0",
+		"latest":       "5.0.0",
+		"^2.5":         "2.6.1",
+		"~1.2":         "1.2.10",
+		"1.2":          "1.2.10",
+		"1.x":          "1.2.10",
+		"=1.2.3":       "1.2.3",
+		">=4.18 <5":    "4.19.0",
+		">= 4.18, < 5": "4.19.0",
+		"<=1.2":        "1.2.10",
+		">1.2 <2":      "",
+		"^1 || ^2":     "2.6.1",
+		"1.3.0-rc.1":   "v1.3.0-rc.1",
+		"^3":           "",
+	} {
+		c, err := pkg.ParseConstraint(constraint)
+		g.E(err)
+
+		v, _ := c.Latest(list)
+		g.Desc(constraint).Eq(v, expected)
+	}
+
+	_, err := pkg.ParseConstraint(">=a.b")
+	g.Err(err)
+
+	a, _ := pkg.ParseSemver("1.0.0-alpha.2")
+	b, _ := pkg.ParseSemver("1.0.0-alpha.10")
+	g.Eq(a.Compare(b), -1)
+}
@@ -0,0 +1,80 @@
+package pkg_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/ysmood/got"
+)
+
+func getTmpDir(g got.G) string {
+	return filepath.Join("tmp", g.RandStr(8))
+}
+
+// tarGz creates a tar.gz bundle from the map of path to content.
+func tarGz(g got.G, files map[string]string) []byte {
+	buf := bytes.NewBuffer(nil)
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+
+	for name, content := range files {
+		g.E(tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     0755,
+			Size:     int64(len(content)),
+		}))
+		g.E(tw.Write([]byte(content)))
+	}
+
+	g.E(tw.Close())
+	g.E(gz.Close())
+
+	return buf.Bytes()
+}
+
+func sha256Hex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+type bufLogger struct {
+	buf string
+}
+
+func (b *bufLogger) Println(msg ...interface{}) {
+	b.buf += fmt.Sprintln(msg...)
+}
+
+// versionScript is a fake executable that prints a banner to stderr.
+func versionScript(version string) string {
+	return "#!/bin/sh\necho 'Tool - the example' >&2\necho \"tool version v" + version + " built at 2025\" >&2\n"
+}
+
+// serveTool serves the bundles "/tool/<version>.tar.gz", each has the "tool" of [versionScript] and
+// the extra files whose content is the version. The builds map the version of the URL to the one
+// that the tool reports, such as a wrong build.
+func serveTool(g got.G, builds map[string]string, files ...string) *got.Router {
+	s := g.Serve()
+	s.Mux.HandleFunc("/tool/", func(rw http.ResponseWriter, r *http.Request) {
+		v := strings.TrimSuffix(filepath.Base(r.URL.Path), ".tar.gz")
+		if b, has := builds[v]; has {
+			v = b
+		}
+
+		bundle := map[string]string{"tool": versionScript(v)}
+		for _, f := range files {
+			bundle[f] = v
+		}
+
+		g.E(rw.Write(tarGz(g, bundle)))
+	})
+	return s
+}
@@ -0,0 +1,335 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ysmood/fetchup"
+)
+
+// Record is an installation saved in the [Options.StateFile].
+type Record struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+
+	// Path is the installed path of the primary executable, the records are keyed by it and the Version,
+	// so that the same tool can be installed to different dirs.
+	Path string `json:"path"`
+
+	// Platform is the target of the installation, such as "linux/arm64".
+	Platform string `json:"platform"`
+
+	// URL is the one that the bundle is downloaded from.
+	URL string `json:"url"`
+
+	// SHA256 is the hex sha256 digest of the downloaded bundle.
+	SHA256 string `json:"sha256"`
+
+	// Files are the installed files and their digests.
+	Files []RecordFile `json:"files"`
+
+	// Links are the links created by the Tree or Versioned mode.
+	Links []string `json:"links,omitempty"`
+
+	// Dirs are the installed dirs, they are removed with everything in them on uninstall.
+	Dirs []string `json:"dirs,omitempty"`
+
+	InstalledAt time.Time `json:"installed_at"`
+}
+
+type RecordFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// ErrModified is returned by [Verify] when the installed files are missing or changed.
+type ErrModified struct {
+	Paths []string
+}
+
+func (e *ErrModified) Error() string {
+	return fmt.Sprintf("installed files are missing or modified: %s", strings.Join(e.Paths, ", "))
+}
+
+// List returns the installations recorded in the state file, sorted by name, version, and path.
+func List(opts Options) ([]*Record, error) {
+	return readState(Defaults(opts).StateFile)
+}
+
+// Uninstall removes all the recorded files, links, and dirs of the tool, and its records in the state file.
+func Uninstall(opts Options, name string) error {
+	opts = Defaults(opts)
+
+	unlock, err := opts.lockState()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	list, err := readState(opts.StateFile)
+	if err != nil {
+		return err
+	}
+
+	rest := []*Record{}
+	found := false
+	for _, r := range list {
+		if r.Name != name {
+			rest = append(rest, r)
+			continue
+		}
+		found = true
+
+		paths := append([]string{}, r.Links...)
+		for _, f := range r.Files {
+			paths = append(paths, f.Path)
+		}
+		paths = append(paths, r.Dirs...)
+
+		for _, p := range paths {
+			err = os.RemoveAll(p)
+			if err != nil {
+				return err
+			}
+			_ = os.Remove(p + ".bak")
+		}
+
+		// remove the parent dir of the versions if it's empty
+		for _, d := range r.Dirs {
+			_ = os.Remove(filepath.Dir(d))
+		}
+
+		opts.Logger.Println(fmt.Sprintf("uninstalled version %s of %s", r.Version, name))
+	}
+
+	if !found {
+		return fmt.Errorf("%s is not installed", name)
+	}
+
+	return writeState(opts.StateFile, rest)
+}
+
+// Verify hashes the installed files of the tool again and compares them with the recorded digests.
+// If name is empty, all the tools will be verified. It returns [ErrModified] if any file doesn't match.
+func Verify(opts Options, name string) error {
+	opts = Defaults(opts)
+
+	list, err := readState(opts.StateFile)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	bad := []string{}
+	for _, r := range list {
+		if name != "" && r.Name != name {
+			continue
+		}
+		found = true
+
+		for _, f := range r.Files {
+			sum, err := hashFile(f.Path)
+			if err != nil || sum != f.SHA256 {
+				bad = append(bad, f.Path)
+			}
+		}
+	}
+
+	if !found && name != "" {
+		return fmt.Errorf("%s is not installed", name)
+	}
+
+	if len(bad) > 0 {
+		return &ErrModified{bad}
+	}
+
+	return nil
+}
+
+// saveRecord adds the record to the state file. If replace is true, the other versions installed at the same path
+// are removed from the state file, otherwise only the same version is replaced.
+func saveRecord(opts Options, r *Record, replace bool) error {
+	unlock, err := opts.lockState()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	list, err := readState(opts.StateFile)
+	if err != nil {
+		return err
+	}
+
+	rest := []*Record{}
+	for _, old := range list {
+		if old.Path != r.Path || (!replace && old.Version != r.Version) {
+			rest = append(rest, old)
+		}
+	}
+
+	return writeState(opts.StateFile, append(rest, r))
+}
+
+// removeRecord removes the record of the version installed at the path from the state file.
+func removeRecord(opts Options, path, version string) error {
+	unlock, err := opts.lockState()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	list, err := readState(opts.StateFile)
+	if err != nil {
+		return err
+	}
+
+	rest := []*Record{}
+	for _, r := range list {
+		if r.Path != path || r.Version != version {
+			rest = append(rest, r)
+		}
+	}
+
+	return writeState(opts.StateFile, rest)
+}
+
+func findRecord(opts Options, path, version string) *Record {
+	list, _ := readState(opts.StateFile)
+	for _, r := range list {
+		if r.Path == path && r.Version == version {
+			return r
+		}
+	}
+	return nil
+}
+
+// installedRecord returns the record of the executable at path for the target platform,
+// it returns nil if the executable is changed since it's recorded.
+func installedRecord(opts Options, path string) *Record {
+	list, _ := readState(opts.StateFile)
+	for _, r := range list {
+		if r.Path != path || r.Platform != opts.platformName() {
+			continue
+		}
+
+		for _, f := range r.Files {
+			if f.Path == path {
+				if sum, err := hashFile(path); err == nil && sum == f.SHA256 {
+					return r
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// newRecord hashes the files under the paths, the dirs will be walked. The path is the primary executable.
+func newRecord(opts Options, res *fetchup.Result, path string, paths []string) (*Record, error) {
+	r := &Record{
+		Name:        opts.Name,
+		Version:     opts.Version,
+		Path:        path,
+		Platform:    opts.platformName(),
+		Files:       []RecordFile{},
+		InstalledAt: time.Now(),
+	}
+
+	if res != nil {
+		r.URL = res.URL
+		r.SHA256 = res.SHA256
+	}
+
+	for _, p := range paths {
+		err := filepath.Walk(p, func(p string, info os.FileInfo, err error) error {
+			if err != nil || !info.Mode().IsRegular() {
+				return err
+			}
+
+			sum, err := hashFile(p)
+			if err != nil {
+				return err
+			}
+
+			r.Files = append(r.Files, RecordFile{Path: p, SHA256: sum})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash installed files: %w", err)
+		}
+	}
+
+	return r, nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func readState(path string) ([]*Record, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return []*Record{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	list := []*Record{}
+	err = json.Unmarshal(b, &list)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+
+	return list, nil
+}
+
+// writeState writes to a temp file then renames it, so that the state file is never half written.
+func writeState(path string, list []*Record) error {
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		if list[i].Version != list[j].Version {
+			return list[i].Version < list[j].Version
+		}
+		return list[i].Path < list[j].Path
+	})
+
+	b, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	tmp := fmt.Sprintf("%s.%d.tmp", path, time.Now().UnixNano())
+
+	err = os.WriteFile(tmp, b, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,160 @@
+package pkg_test
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ysmood/fetchup"
+	"github.com/ysmood/fetchup/pkg"
+	"github.com/ysmood/got"
+)
+
+func TestState(t *testing.T) {
+	g := got.T(t)
+
+	bundle := tarGz(g, map[string]string{
+		"bin/protoc":             "protoc",
+		"include/google/a.proto": "a",
+	})
+
+	s := g.Serve()
+	s.Mux.HandleFunc("/protoc.tar.gz", func(rw http.ResponseWriter, r *http.Request) {
+		g.E(rw.Write(bundle))
+	})
+
+	dir := getTmpDir(g)
+	bin := filepath.Join(dir, "bin")
+
+	opts := pkg.Options{
+		Logger:       fetchup.LoggerQuiet,
+		InstallToDir: bin,
+		Version:      "1.0.0",
+		URLs:         pkg.NewTemplates(s.URL("/protoc.tar.gz")),
+		BundleBin:    pkg.NewTemplates("bin", "protoc"),
+		Share:        []pkg.BundleFile{{Path: pkg.NewTemplates("include")}},
+	}
+
+	g.E(pkg.InstallWithOptions(opts))
+
+	list, err := pkg.List(pkg.Options{InstallToDir: bin})
+	g.E(err)
+	g.Len(list, 1)
+	g.Eq(list[0].Name, "protoc")
+	g.Eq(list[0].Version, "1.0.0")
+	g.Eq(list[0].Path, filepath.Join(bin, "protoc"))
+	g.Eq(list[0].URL, s.URL("/protoc.tar.gz"))
+	g.Eq(list[0].SHA256, sha256Hex(bundle))
+	g.Len(list[0].Files, 2)
+	g.Eq(list[0].Files[0].SHA256, sha256Hex([]byte("protoc")))
+
+	g.E(pkg.Verify(opts, ""))
+
+	g.WriteFile(filepath.Join(dir, "share", "protoc", "include", "google", "a.proto"), "changed")
+
+	e := &pkg.ErrModified{}
+	g.True(errors.As(pkg.Verify(opts, "protoc"), &e))
+	g.Eq(e.Paths, []string{filepath.Join(dir, "share", "protoc", "include", "google", "a.proto")})
+
+	g.E(pkg.Uninstall(opts, "protoc"))
+	g.False(g.PathExists(filepath.Join(bin, "protoc")))
+	g.False(g.PathExists(filepath.Join(dir, "share", "protoc")))
+
+	list, err = pkg.List(opts)
+	g.E(err)
+	g.Len(list, 0)
+
+	g.Eq(pkg.Uninstall(opts, "protoc").Error(), "protoc is not installed")
+}
+
+func TestStateSharedFile(t *testing.T) {
+	g := got.T(t)
+
+	s := g.Serve()
+	s.Mux.HandleFunc("/tool.tar.gz", func(rw http.ResponseWriter, r *http.Request) {
+		g.E(rw.Write(tarGz(g, map[string]string{"tool": "tool"})))
+	})
+
+	dir := getTmpDir(g)
+	state := filepath.Join(dir, "state.json")
+
+	install := func(bin string) {
+		g.E(pkg.InstallWithOptions(pkg.Options{
+			Logger:       fetchup.LoggerQuiet,
+			InstallToDir: filepath.Join(dir, bin),
+			StateFile:    state,
+			Exists:       func(string) bool { return false },
+			URLs:         pkg.NewTemplates(s.URL("/tool.tar.gz")),
+			BundleBin:    pkg.NewTemplates("tool"),
+		}))
+	}
+
+	// the same tool in sibling dirs has a record for each of them
+	install("a")
+	install("b")
+	install("a")
+
+	list, err := pkg.List(pkg.Options{StateFile: state})
+	g.E(err)
+	g.Len(list, 2)
+	g.Eq(list[0].Path, filepath.Join(dir, "a", "tool"))
+	g.Eq(list[1].Path, filepath.Join(dir, "b", "tool"))
+}
+
+func TestStateVersioned(t *testing.T) {
+	g := got.T(t)
+
+	if runtime.GOOS == "windows" {
+		g.Skip("symlinks require privileges on windows")
+	}
+
+	s := g.Serve()
+	s.Mux.HandleFunc("/", func(rw http.ResponseWriter, r *http.Request) {
+		v := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/lint-"), ".tar.gz")
+		g.E(rw.Write(tarGz(g, map[string]string{"lint": "lint " + v})))
+	})
+
+	dir := getTmpDir(g)
+	bin := filepath.Join(dir, "bin")
+
+	opts := pkg.Options{
+		Logger:       fetchup.LoggerQuiet,
+		InstallToDir: bin,
+		Versioned:    true,
+		URLs:         pkg.NewTemplates(s.URL("/lint-{{.Version}}.tar.gz")),
+		BundleBin:    pkg.NewTemplates("lint"),
+	}
+
+	for _, v := range []string{"1.0.0", "2.0.0", "1.0.0"} {
+		o := opts
+		o.Version = v
+		g.E(pkg.InstallWithOptions(o))
+	}
+
+	list, err := pkg.List(opts)
+	g.E(err)
+	g.Len(list, 2)
+	g.Eq(list[0].Version, "1.0.0")
+	g.Eq(list[0].URL, s.URL("/lint-1.0.0.tar.gz"))
+	g.Eq(list[1].Version, "2.0.0")
+
+	g.E(pkg.Verify(opts, "lint"))
+
+	_, err = pkg.Prune(opts, 0)
+	g.E(err)
+
+	list, err = pkg.List(opts)
+	g.E(err)
+	g.Len(list, 1)
+
+	g.E(pkg.Uninstall(opts, "lint"))
+	g.False(g.PathExists(filepath.Join(bin, "lint")))
+
+	_, err = os.Lstat(filepath.Join(bin, "lint"))
+	g.True(os.IsNotExist(err))
+	g.False(g.PathExists(filepath.Join(dir, "fetchup", "lint")))
+}
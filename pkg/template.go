@@ -0,0 +1,240 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Template is a [text/template] with the functions below:
+//
+//	title        "darwin" to "Darwin"
+//	upper        "darwin" to "DARWIN"
+//	lower        "Darwin" to "darwin"
+//	trimPrefix   {{trimPrefix "v" .Tag}} or {{.Tag | trimPrefix "v"}}, "v1.2.3" to "1.2.3"
+//	replace      {{replace "_" "-" .OS}}, replaces all the "_" with "-"
+//	default      {{default "stable" .Channel}}, "stable" if .Channel is empty, use {{default "stable" (index . "Channel")}}
+//	             if the key is optional
+//	env          {{env "HOME"}}, the value of the env var
+//	semverMajor  {{semverMajor .Version}}, "1.2.3" to 1
+//	semverMinor  {{semverMinor .Version}}, "1.2.3" to 2
+//	osAlias      the alias of the OS in the [Options.Aliases], such as {{osAlias .OS}}
+//	archAlias    the alias of the Arch for the OS in the [Options.Aliases], such as {{archAlias .Arch}}
+//
+// Referring to a missing key of the args is an error, such as a typo of {{.Verison}}.
+type Template struct {
+	tpl *template.Template
+
+	// err is the parse error of [NewTemplate], it's returned by [Template.Render].
+	err error
+}
+
+// aliasesKey is the key of the [Aliases] in the template args for the alias functions.
+const aliasesKey = "Aliases"
+
+// funcs returns the template functions, the alias functions use the aliases in the data.
+func funcs(data map[string]any) template.FuncMap {
+	aliases, _ := data[aliasesKey].(*Aliases)
+	goos, _ := data["OS"].(string)
+
+	return template.FuncMap{
+		"title":       title,
+		"upper":       strings.ToUpper,
+		"lower":       strings.ToLower,
+		"trimPrefix":  func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"replace":     func(from, to, s string) string { return strings.ReplaceAll(s, from, to) },
+		"default":     defaultValue,
+		"env":         os.Getenv,
+		"semverMajor": semverMajor,
+		"semverMinor": semverMinor,
+		"osAlias":     aliases.os,
+		"archAlias":   func(arch string) string { return aliases.arch(goos, arch) },
+	}
+}
+
+// NewTemplate parses the template, if it fails the error is returned by [Template.Render].
+// Use [ParseTemplate] to get the error early.
+func NewTemplate(tpl string) Template {
+	t, err := ParseTemplate(tpl)
+	if err != nil {
+		return Template{err: err}
+	}
+	return t
+}
+
+func NewTemplates(list ...string) []Template {
+	templates := make([]Template, len(list))
+	for i, tpl := range list {
+		templates[i] = NewTemplate(tpl)
+	}
+	return templates
+}
+
+// ParseTemplate parses the template and returns the syntax error.
+func ParseTemplate(s string) (Template, error) {
+	tpl, err := template.New("tpl").Funcs(funcs(nil)).Option("missingkey=error").Parse(s)
+	if err != nil {
+		return Template{}, fmt.Errorf("failed to parse template %q: %w", s, err)
+	}
+	return Template{tpl: tpl}, nil
+}
+
+// literalTemplate renders s as it is, even if s contains "{{", such as a URL that's already rendered.
+func literalTemplate(s string) Template {
+	return NewTemplate("{{" + strconv.Quote(s) + "}}")
+}
+
+// ParseTemplates is the same as [ParseTemplate], but for a list.
+func ParseTemplates(list ...string) ([]Template, error) {
+	templates := []Template{}
+	for _, s := range list {
+		t, err := ParseTemplate(s)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+func (t Template) IsZero() bool {
+	return t.tpl == nil && t.err == nil
+}
+
+func (t Template) Render(data map[string]any) (string, error) {
+	if t.err != nil {
+		return "", t.err
+	}
+
+	if t.tpl == nil {
+		return "", fmt.Errorf("empty template")
+	}
+
+	buf := bytes.NewBuffer(nil)
+
+	// bind the functions to the data
+	tpl, err := t.tpl.Clone()
+	if err != nil {
+		return "", err
+	}
+
+	err = tpl.Funcs(funcs(data)).Execute(buf, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+func defaultValue(def string, v any) string {
+	if v == nil {
+		return def
+	}
+
+	s := fmt.Sprint(v)
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+func semverMajor(v string) (int, error) {
+	s, _, ok := parsePartial(v)
+	if !ok {
+		return 0, fmt.Errorf("invalid semantic version: %q", v)
+	}
+	return s.Major, nil
+}
+
+func semverMinor(v string) (int, error) {
+	s, _, ok := parsePartial(v)
+	if !ok {
+		return 0, fmt.Errorf("invalid semantic version: %q", v)
+	}
+	return s.Minor, nil
+}
+
+func title(s string) string {
+	if s == "" {
+		return s
+	}
+
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// Aliases maps the Go names of the platforms to the names used by the upstream.
+// The names that aren't in the maps are used as they are.
+type Aliases struct {
+	// OS is keyed by GOOS, such as "darwin".
+	OS map[string]string
+
+	// Arch is keyed by GOARCH, such as "amd64", or by "<GOOS>/<GOARCH>" for a platform, such as "darwin/arm64".
+	// The "<GOOS>/<GOARCH>" key is preferred.
+	Arch map[string]string
+}
+
+// UnameAliases are the names printed by "uname -s" and "uname -m", such as "Linux" and "x86_64".
+var UnameAliases = &Aliases{
+	OS: map[string]string{
+		"linux":   "Linux",
+		"darwin":  "Darwin",
+		"windows": "Windows",
+		"freebsd": "FreeBSD",
+	},
+	Arch: map[string]string{
+		"amd64": "x86_64",
+		"arm64": "aarch64",
+		"386":   "i386",
+		"arm":   "armv7",
+
+		// Apple Silicon prints "arm64"
+		"darwin/arm64": "arm64",
+	},
+}
+
+// RustTripleAliases are the parts of the Rust target triples, such as "x86_64" and "unknown-linux-gnu"
+// for "x86_64-unknown-linux-gnu".
+var RustTripleAliases = &Aliases{
+	OS: map[string]string{
+		"linux":   "unknown-linux-gnu",
+		"darwin":  "apple-darwin",
+		"windows": "pc-windows-msvc",
+		"freebsd": "unknown-freebsd",
+	},
+	Arch: map[string]string{
+		"amd64": "x86_64",
+		"arm64": "aarch64",
+		"386":   "i686",
+		"arm":   "armv7",
+	},
+}
+
+func (a *Aliases) os(name string) string {
+	if a == nil {
+		return name
+	}
+	return alias(a.OS, name)
+}
+
+func (a *Aliases) arch(goos, name string) string {
+	if a == nil {
+		return name
+	}
+	if v, ok := a.Arch[goos+"/"+name]; ok {
+		return v
+	}
+	return alias(a.Arch, name)
+}
+
+func alias(m map[string]string, name string) string {
+	if v, ok := m[name]; ok {
+		return v
+	}
+	return name
+}
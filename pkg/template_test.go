@@ -0,0 +1,79 @@
+package pkg_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/ysmood/fetchup/pkg"
+	"github.com/ysmood/got"
+)
+
+func TestAliases(t *testing.T) {
+	g := got.T(t)
+
+	render := func(aliases *pkg.Aliases, tpl string) string {
+		g.Helper()
+		args := map[string]any{}
+		pkg.SetDefaultTemplateArgs(pkg.Options{Aliases: aliases, TemplateArgs: args})
+		s, err := pkg.NewTemplate(tpl).Render(args)
+		g.E(err)
+		return s
+	}
+
+	g.Eq(render(pkg.RustTripleAliases, `{{archAlias "arm64"}}-{{osAlias "darwin"}}`), "aarch64-apple-darwin")
+	g.Eq(render(pkg.UnameAliases, `{{osAlias "linux"}}_{{archAlias "amd64"}}`), "Linux_x86_64")
+	g.Eq(render(pkg.UnameAliases, `{{archAlias "riscv64"}}`), "riscv64")
+
+	darwin := func(tpl string) string {
+		g.Helper()
+		args := map[string]any{}
+		pkg.SetDefaultTemplateArgs(pkg.Options{Aliases: pkg.UnameAliases, TemplateArgs: args, TargetOS: "darwin", TargetArch: "arm64"})
+		s, err := pkg.NewTemplate(tpl).Render(args)
+		g.E(err)
+		return s
+	}
+	g.Eq(darwin(`{{.OSAlias}}_{{.ArchAlias}} {{archAlias .Arch}}`), "Darwin_arm64 arm64")
+	g.Eq(render(nil, `{{archAlias "amd64"}} {{.ArchAlias}}`), "amd64 "+runtime.GOARCH)
+	g.Eq(render(nil, `{{title "darwin"}} {{upper "win64"}}`), "Darwin WIN64")
+	g.Eq(render(nil, `[{{title ""}}]`), "[]")
+
+	custom := &pkg.Aliases{OS: map[string]string{runtime.GOOS: "myos"}}
+	g.Eq(render(custom, `{{.OSAlias}}-{{osAlias .OS}}-{{.OS}}`), "myos-myos-"+runtime.GOOS)
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	g := got.T(t)
+
+	g.Setenv("FETCHUP_TEST_MIRROR", "mirror.example.com")
+
+	args := map[string]any{"Tag": "v1.22.3", "OS": "Darwin", "Channel": ""}
+
+	render := func(tpl string) string {
+		g.Helper()
+		s, err := pkg.NewTemplate(tpl).Render(args)
+		g.E(err)
+		return s
+	}
+
+	g.Eq(render(`{{.Tag | trimPrefix "v"}}`), "1.22.3")
+	g.Eq(render(`go{{semverMajor .Tag}}.{{semverMinor .Tag}}`), "go1.22")
+	g.Eq(render(`{{lower .OS}}`), "darwin")
+	g.Eq(render(`{{replace "." "_" .Tag}}`), "v1_22_3")
+	g.Eq(render(`{{default "stable" .Channel}}-{{default "x" (index . "Missing")}}`), "stable-x")
+	g.Eq(render(`https://{{env "FETCHUP_TEST_MIRROR"}}/a`), "https://mirror.example.com/a")
+
+	_, err := pkg.NewTemplate(`{{.Verison}}`).Render(args)
+	g.Has(err.Error(), `map has no entry for key "Verison"`)
+
+	_, err = pkg.NewTemplate(`{{semverMajor .OS}}`).Render(args)
+	g.Has(err.Error(), `invalid semantic version: "Darwin"`)
+
+	_, err = pkg.ParseTemplate(`{{.Tag`)
+	g.Has(err.Error(), `failed to parse template "{{.Tag"`)
+
+	// the parse error is deferred to the render instead of a panic
+	tpl := pkg.NewTemplate(`{{unknownFunc .Tag}}`)
+	g.False(tpl.IsZero())
+	_, err = tpl.Render(args)
+	g.Has(err.Error(), `function "unknownFunc" not defined`)
+}
@@ -0,0 +1,313 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ysmood/fetchup"
+)
+
+// currentLink is the name of the link that points to the active version dir.
+const currentLink = "current"
+
+func (opts Options) toolDir() string {
+	return filepath.Join(opts.VersionsDir, opts.Name)
+}
+
+func (opts Options) versionDir(version string) string {
+	if version == "" {
+		version = "default"
+	}
+	return filepath.Join(opts.toolDir(), version)
+}
+
+func (opts Options) currentDir() string {
+	return filepath.Join(opts.toolDir(), currentLink)
+}
+
+// installVersion keeps the version in its own dir, then activates it.
+// If the version dir already exists, it only activates it.
+func installVersion(opts Options, f *fetchup.Fetchup, bins, share []*renderedFile) error {
+	dir := opts.versionDir(opts.Version)
+
+	if readLink(opts.currentDir()) == filepath.Base(dir) && linked(opts, bins) {
+		opts.Logger.Println(fmt.Sprintf("version %s of %s is already active, skipping installation", opts.Version, opts.Name))
+		return nil
+	}
+
+	_, err := os.Stat(dir)
+	if err == nil {
+		opts.Logger.Println(fmt.Sprintf("version %s of %s is already installed at %s", opts.Version, opts.Name, dir))
+
+		err = activate(opts, bins, share)
+		if err != nil {
+			return err
+		}
+
+		return recordVersion(opts, nil, bins, share)
+	}
+
+	err = os.MkdirAll(filepath.Dir(dir), 0755)
+	if err != nil {
+		return err
+	}
+
+	// Download to a temp dir then rename it, so the version dir is always complete.
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), filepath.Base(dir)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+
+	var res *fetchup.Result
+	if opts.Tree && opts.CacheDir == "" {
+		res, err = f.WithSaveTo(tmp).FetchResult()
+	} else if opts.Tree {
+		res, err = fetchFiles(opts, f, tmp, nil)
+	} else {
+		res, err = fetchFiles(opts, f, tmp, append(append([]*renderedFile{}, bins...), share...))
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, b := range bins {
+		if _, err := os.Stat(filepath.Join(tmp, b.src)); err != nil {
+			return fmt.Errorf("executable not found in the bundle: %w", err)
+		}
+	}
+
+	// Verify before it's moved into place, so the active version is untouched on failure.
+	if opts.Verify != nil {
+		err = opts.Verify(opts.Ctx, filepath.Join(tmp, bins[0].src), opts.Version)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = os.Rename(tmp, dir)
+	if err != nil {
+		return err
+	}
+
+	err = activate(opts, bins, share)
+	if err != nil {
+		return err
+	}
+
+	return recordVersion(opts, res, bins, share)
+}
+
+// fetchFiles downloads the bundle and only keeps the files in the dir, the whole bundle is kept if files is nil.
+func fetchFiles(opts Options, f *fetchup.Fetchup, dir string, files []*renderedFile) (*fetchup.Result, error) {
+	res, from, done, err := fetch(opts, f)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	if files == nil {
+		err = copyTree(from, dir)
+		if err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+
+	for _, file := range files {
+		err = copyTree(filepath.Join(from, file.src), filepath.Join(dir, file.src))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// recordVersion saves the files in the version dir and the links to the state file.
+// If res is nil, the source of the existing record will be kept.
+func recordVersion(opts Options, res *fetchup.Result, bins, share []*renderedFile) error {
+	dir := opts.versionDir(opts.Version)
+
+	paths := []string{}
+	links := []string{opts.currentDir()}
+	for _, f := range append(append([]*renderedFile{}, bins...), share...) {
+		paths = append(paths, filepath.Join(dir, f.src))
+		links = append(links, f.dst)
+	}
+
+	r, err := newRecord(opts, res, bins[0].dst, paths)
+	if err != nil {
+		return err
+	}
+
+	if old := findRecord(opts, bins[0].dst, opts.Version); res == nil && old != nil {
+		r.URL, r.SHA256, r.InstalledAt = old.URL, old.SHA256, old.InstalledAt
+	}
+
+	r.Links = links
+	r.Dirs = []string{dir}
+
+	return saveRecord(opts, r, false)
+}
+
+// activate points the current link to the version dir, and links the executables and share files to it.
+func activate(opts Options, bins, share []*renderedFile) error {
+	err := link(filepath.Base(opts.versionDir(opts.Version)), opts.currentDir())
+	if err != nil {
+		return fmt.Errorf("failed to activate version %s of %s: %w", opts.Version, opts.Name, err)
+	}
+
+	current, err := filepath.Abs(opts.currentDir())
+	if err != nil {
+		return err
+	}
+
+	for _, f := range append(append([]*renderedFile{}, bins...), share...) {
+		err = link(filepath.Join(current, f.src), f.dst)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// linked reports if all the executables are linked to the current dir.
+func linked(opts Options, bins []*renderedFile) bool {
+	current, err := filepath.Abs(opts.currentDir())
+	if err != nil {
+		return false
+	}
+
+	for _, b := range bins {
+		if readLink(b.dst) != filepath.Join(current, b.src) {
+			return false
+		}
+	}
+	return true
+}
+
+// InstalledVersions returns the versions of the tool kept by the Tree or Versioned mode, from old to new.
+func InstalledVersions(opts Options) ([]string, error) {
+	opts, err := Defaults(opts).named()
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := os.ReadDir(opts.toolDir())
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	versions := []string{}
+	for _, e := range list {
+		name := e.Name()
+		if name == currentLink || strings.Contains(name, ".tmp-") || !e.IsDir() {
+			continue
+		}
+		versions = append(versions, name)
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		a, aOK := ParseSemver(versions[i])
+		b, bOK := ParseSemver(versions[j])
+		if aOK && bOK {
+			return a.Compare(b) < 0
+		}
+		if aOK != bOK {
+			return !aOK
+		}
+		return versions[i] < versions[j]
+	})
+
+	return versions, nil
+}
+
+// ActiveVersion returns the version that the current link points to, it's empty if no version is active.
+func ActiveVersion(opts Options) string {
+	opts, err := Defaults(opts).named()
+	if err != nil {
+		return ""
+	}
+	return readLink(opts.currentDir())
+}
+
+// Activate switches the executables to an installed version of the tool.
+func Activate(opts Options, version string) error {
+	opts.Version = version
+	opts, err := Defaults(opts).named()
+	if err != nil {
+		return err
+	}
+
+	dir := opts.versionDir(version)
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("version %s of %s is not installed: %w", version, opts.Name, err)
+	}
+
+	bins, err := opts.bins()
+	if err != nil {
+		return err
+	}
+
+	share, err := opts.share()
+	if err != nil {
+		return err
+	}
+
+	err = activate(opts, bins, share)
+	if err != nil {
+		return err
+	}
+
+	return recordVersion(opts, nil, bins, share)
+}
+
+// Prune removes the installed versions except the active one and the newest keep ones.
+// It returns the removed versions.
+func Prune(opts Options, keep int) ([]string, error) {
+	opts, err := Defaults(opts).named()
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := InstalledVersions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	bins, err := opts.bins()
+	if err != nil {
+		return nil, err
+	}
+
+	active := ActiveVersion(opts)
+
+	removed := []string{}
+	for i, v := range versions {
+		if v == active || i >= len(versions)-keep {
+			continue
+		}
+
+		err = os.RemoveAll(opts.versionDir(v))
+		if err != nil {
+			return removed, err
+		}
+
+		err = removeRecord(opts, bins[0].dst, v)
+		if err != nil {
+			return removed, err
+		}
+
+		opts.Logger.Println(fmt.Sprintf("removed version %s of %s", v, opts.Name))
+		removed = append(removed, v)
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,139 @@
+package pkg_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ysmood/fetchup"
+	"github.com/ysmood/fetchup/pkg"
+	"github.com/ysmood/got"
+)
+
+func TestTree(t *testing.T) {
+	g := got.T(t)
+
+	if runtime.GOOS == "windows" {
+		g.Skip("symlinks require privileges on windows")
+	}
+
+	hits := 0
+	s := g.Serve()
+	s.Mux.HandleFunc("/", func(rw http.ResponseWriter, r *http.Request) {
+		hits++
+		v := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/node-v"), ".tar.gz")
+		g.E(rw.Write(tarGz(g, map[string]string{
+			"node-v" + v + "/bin/node":       "node " + v,
+			"node-v" + v + "/bin/npm":        "npm " + v,
+			"node-v" + v + "/lib/index.js":   "lib " + v,
+			"node-v" + v + "/include/node.h": "header " + v,
+		})))
+	})
+
+	dir := getTmpDir(g)
+	bin := filepath.Join(dir, "bin")
+
+	install := func(version string) {
+		g.Helper()
+		g.E(pkg.InstallWithOptions(pkg.Options{
+			Logger:       fetchup.LoggerQuiet,
+			InstallToDir: bin,
+			Version:      version,
+			Tree:         true,
+			URLs:         pkg.NewTemplates(s.URL("/node-v{{.Version}}.tar.gz")),
+			Bins: []pkg.BundleFile{
+				{Path: pkg.NewTemplates("node-v{{.Version}}", "bin", "node")},
+				{Path: pkg.NewTemplates("node-v{{.Version}}", "bin", "npm")},
+			},
+			Share: []pkg.BundleFile{
+				{Path: pkg.NewTemplates("node-v{{.Version}}", "include")},
+			},
+		}))
+	}
+
+	install("1.0.0")
+	g.Eq(g.Read(filepath.Join(bin, "node")).String(), "node 1.0.0")
+	g.Eq(g.Read(filepath.Join(bin, "npm")).String(), "npm 1.0.0")
+	g.Eq(g.Read(filepath.Join(dir, "share", "node", "include", "node.h")).String(), "header 1.0.0")
+	g.Eq(g.Read(filepath.Join(dir, "fetchup", "node", "current", "node-v1.0.0", "lib", "index.js")).String(), "lib 1.0.0")
+
+	install("2.0.0")
+	g.Eq(g.Read(filepath.Join(bin, "node")).String(), "node 2.0.0")
+	downloaded := hits
+
+	// switching back is only a link flip
+	install("1.0.0")
+	g.Eq(g.Read(filepath.Join(bin, "node")).String(), "node 1.0.0")
+	g.Eq(g.Read(filepath.Join(dir, "fetchup", "node", "2.0.0", "node-v2.0.0", "lib", "index.js")).String(), "lib 2.0.0")
+	g.Eq(hits, downloaded)
+
+	install("1.0.0")
+	g.Eq(hits, downloaded)
+
+	// no temp dirs are left
+	list, err := os.ReadDir(filepath.Join(dir, "fetchup", "node"))
+	g.E(err)
+	g.Len(list, 3)
+}
+
+func TestVersioned(t *testing.T) {
+	g := got.T(t)
+
+	if runtime.GOOS == "windows" {
+		g.Skip("symlinks require privileges on windows")
+	}
+
+	s := g.Serve()
+	s.Mux.HandleFunc("/", func(rw http.ResponseWriter, r *http.Request) {
+		v := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/lint-"), ".tar.gz")
+		g.E(rw.Write(tarGz(g, map[string]string{
+			"lint/lint":       "lint " + v,
+			"lint/README.md":  "readme",
+			"lint/LICENSE.md": "license",
+		})))
+	})
+
+	dir := getTmpDir(g)
+	bin := filepath.Join(dir, "bin")
+
+	opts := pkg.Options{
+		Logger:       fetchup.LoggerQuiet,
+		InstallToDir: bin,
+		Versioned:    true,
+		URLs:         pkg.NewTemplates(s.URL("/lint-{{.Version}}.tar.gz")),
+		BundleBin:    pkg.NewTemplates("lint", "lint"),
+	}
+
+	for _, v := range []string{"1.10.0", "1.9.0", "2.0.0"} {
+		o := opts
+		o.Version = v
+		g.E(pkg.InstallWithOptions(o))
+		g.Eq(g.Read(filepath.Join(bin, "lint")).String(), "lint "+v)
+	}
+
+	// only the executable is kept
+	g.False(g.PathExists(filepath.Join(dir, "fetchup", "lint", "2.0.0", "lint", "README.md")))
+
+	versions, err := pkg.InstalledVersions(opts)
+	g.E(err)
+	g.Eq(versions, []string{"1.9.0", "1.10.0", "2.0.0"})
+	g.Eq(pkg.ActiveVersion(opts), "2.0.0")
+
+	g.E(pkg.Activate(opts, "1.9.0"))
+	g.Eq(g.Read(filepath.Join(bin, "lint")).String(), "lint 1.9.0")
+	g.Eq(pkg.ActiveVersion(opts), "1.9.0")
+
+	g.Err(pkg.Activate(opts, "3.0.0"))
+
+	removed, err := pkg.Prune(opts, 1)
+	g.E(err)
+	g.Eq(removed, []string{"1.10.0"})
+
+	versions, err = pkg.InstalledVersions(opts)
+	g.E(err)
+	g.Eq(versions, []string{"1.9.0", "2.0.0"})
+	g.Eq(g.Read(filepath.Join(bin, "lint")).String(), "lint 1.9.0")
+}
@@ -0,0 +1,235 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/ysmood/fetchup"
+)
+
+// VersionLister lists the available versions of a tool, it's used to resolve the version constraint.
+type VersionLister interface {
+	ListVersions(ctx context.Context, client *http.Client) ([]string, error)
+}
+
+var _ VersionLister = (*GitHubRelease)(nil)
+
+// ListVersions returns the tags of the releases, drafts and pre-releases are excluded.
+func (gh *GitHubRelease) ListVersions(ctx context.Context, client *http.Client) ([]string, error) {
+	releases, err := gh.releases(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []string{}
+	for _, r := range releases {
+		if !r.Draft && !r.Prerelease {
+			list = append(list, r.TagName)
+		}
+	}
+	return list, nil
+}
+
+// GitHubTags lists the tags of a GitHub repo as versions.
+type GitHubTags struct {
+	// Repo is the "owner/name" of the repo.
+	Repo string
+
+	// Token for the API, default is the env var GITHUB_TOKEN.
+	Token string
+
+	// API is the base URL of the API, default is "https://api.github.com".
+	API string
+}
+
+var _ VersionLister = (*GitHubTags)(nil)
+
+func (t *GitHubTags) ListVersions(ctx context.Context, client *http.Client) ([]string, error) {
+	gh := &GitHubRelease{Repo: t.Repo, Token: t.Token, API: t.API}
+
+	list := []string{}
+
+	u := gh.api() + "/repos/" + t.Repo + "/tags?per_page=100"
+	for page := 0; u != "" && page < 10; page++ {
+		res, err := gh.get(ctx, client, u)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags of %s: %w", t.Repo, err)
+		}
+
+		var tags []struct {
+			Name string `json:"name"`
+		}
+		err = json.NewDecoder(res.Body).Decode(&tags)
+		_ = res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse tags of %s: %w", t.Repo, err)
+		}
+
+		for _, tag := range tags {
+			list = append(list, tag.Name)
+		}
+
+		u = ""
+		if m := regNextLink.FindStringSubmatch(res.Header.Get("Link")); m != nil {
+			u = m[1]
+		}
+	}
+
+	return list, nil
+}
+
+// IndexJSON lists the versions from a JSON index, the index is an array of strings or an array of objects.
+type IndexJSON struct {
+	URL string
+
+	// Field is the key of the version in each object, default is "version".
+	Field string
+
+	// Pattern extracts the version from each item with its first submatch, such as `^go(.+)$`.
+	// Default is to use the item as it is.
+	Pattern *regexp.Regexp
+}
+
+var _ VersionLister = (*IndexJSON)(nil)
+
+func (idx *IndexJSON) ListVersions(ctx context.Context, client *http.Client) ([]string, error) {
+	b, err := getBody(ctx, client, idx.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []json.RawMessage
+	err = json.Unmarshal(b, &items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse version index: %w", err)
+	}
+
+	field := idx.Field
+	if field == "" {
+		field = "version"
+	}
+
+	list := []string{}
+	for _, item := range items {
+		var v string
+		if json.Unmarshal(item, &v) != nil {
+			obj := map[string]json.RawMessage{}
+			if json.Unmarshal(item, &obj) != nil || json.Unmarshal(obj[field], &v) != nil {
+				continue
+			}
+		}
+
+		if v, ok := extract(idx.Pattern, v); ok {
+			list = append(list, v)
+		}
+	}
+
+	return list, nil
+}
+
+// DirListing lists the versions from an HTML page, such as the directory listing of a mirror.
+type DirListing struct {
+	URL string
+
+	// Pattern extracts the versions from the page with its first submatch, such as `href="v(\d+\.\d+\.\d+)/"`.
+	// It's required.
+	Pattern *regexp.Regexp
+}
+
+var _ VersionLister = (*DirListing)(nil)
+
+func (d *DirListing) ListVersions(ctx context.Context, client *http.Client) ([]string, error) {
+	if d.Pattern == nil {
+		return nil, fmt.Errorf("the pattern of the dir listing %s is required", d.URL)
+	}
+
+	b, err := getBody(ctx, client, d.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []string{}
+	for _, m := range d.Pattern.FindAllStringSubmatch(string(b), -1) {
+		if len(m) > 1 {
+			list = append(list, m[1])
+		}
+	}
+
+	return list, nil
+}
+
+func extract(pattern *regexp.Regexp, s string) (string, bool) {
+	if pattern == nil {
+		return s, true
+	}
+
+	m := pattern.FindStringSubmatch(s)
+	if len(m) < 2 {
+		return "", false
+	}
+	return m[1], true
+}
+
+func getBody(ctx context.Context, client *http.Client, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, &fetchup.ErrHTTPStatus{URL: u, StatusCode: res.StatusCode}
+	}
+
+	return io.ReadAll(res.Body)
+}
+
+// IsConstraint reports if the version is a constraint, such as "latest", "^2.5", "1.x", or ">=4.18 <5",
+// rather than an exact version.
+func IsConstraint(version string) bool {
+	if version == "latest" || strings.ContainsAny(version, "^~<>=*| ,") {
+		return true
+	}
+
+	_, parts, ok := parsePartial(version)
+	return ok && parts < 3
+}
+
+// ResolveVersion returns the latest version from the lister that satisfies the constraint,
+// the "v" prefix is removed. If the version is not a constraint, it's returned as it is.
+func ResolveVersion(ctx context.Context, client *http.Client, lister VersionLister, version string) (string, error) {
+	if !IsConstraint(version) {
+		return version, nil
+	}
+
+	if lister == nil {
+		return "", fmt.Errorf("version %q is a constraint, but no version lister is set", version)
+	}
+
+	c, err := ParseConstraint(version)
+	if err != nil {
+		return "", err
+	}
+
+	list, err := lister.ListVersions(ctx, client)
+	if err != nil {
+		return "", err
+	}
+
+	v, ok := c.Latest(list)
+	if !ok {
+		return "", fmt.Errorf("no version matches %q", version)
+	}
+
+	return strings.TrimPrefix(v, "v"), nil
+}
@@ -0,0 +1,85 @@
+package pkg_test
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/ysmood/fetchup"
+	"github.com/ysmood/fetchup/pkg"
+	"github.com/ysmood/got"
+)
+
+func TestVersionListers(t *testing.T) {
+	g := got.T(t)
+
+	s := g.Serve()
+	s.Mux.HandleFunc("/strings.json", func(rw http.ResponseWriter, r *http.Request) {
+		g.E(rw.Write([]byte(`["1.0.0", "1.1.0", "2.0.0"]`)))
+	})
+	s.Mux.HandleFunc("/objects.json", func(rw http.ResponseWriter, r *http.Request) {
+		g.E(rw.Write([]byte(`[{"version": "go1.21.0"}, {"version": "go1.22.1"}, {"other": 1}]`)))
+	})
+	s.Mux.HandleFunc("/dir/", func(rw http.ResponseWriter, r *http.Request) {
+		g.E(rw.Write([]byte(`<a href="v1.2.0/">v1.2.0/</a><a href="v1.10.0/">v1.10.0/</a><a href="../">../</a>`)))
+	})
+	s.Mux.HandleFunc("/repos/org/tool/tags", func(rw http.ResponseWriter, r *http.Request) {
+		g.E(rw.Write([]byte(`[{"name": "v0.1.0"}, {"name": "v0.2.0"}]`)))
+	})
+
+	ctx := context.Background()
+
+	for lister, expected := range map[pkg.VersionLister]string{
+		&pkg.IndexJSON{URL: s.URL("/strings.json")}:                                            "1.1.0",
+		&pkg.IndexJSON{URL: s.URL("/objects.json"), Pattern: regexp.MustCompile(`^go(.+)$`)}:   "1.22.1",
+		&pkg.DirListing{URL: s.URL("/dir/"), Pattern: regexp.MustCompile(`href="(v[^"/]+)/"`)}: "1.10.0",
+		&pkg.GitHubTags{Repo: "org/tool", API: s.URL()}:                                        "",
+		&pkg.DirListing{URL: s.URL("/dir/")}:                                                   "",
+	} {
+		v, err := pkg.ResolveVersion(ctx, http.DefaultClient, lister, "^1")
+		if expected == "" {
+			g.Err(err)
+			continue
+		}
+		g.E(err)
+		g.Eq(v, expected)
+	}
+
+	v, err := pkg.ResolveVersion(ctx, http.DefaultClient, &pkg.GitHubTags{Repo: "org/tool", API: s.URL()}, "latest")
+	g.E(err)
+	g.Eq(v, "0.2.0")
+
+	v, err = pkg.ResolveVersion(ctx, http.DefaultClient, nil, "1.2.3")
+	g.E(err)
+	g.Eq(v, "1.2.3")
+
+	_, err = pkg.ResolveVersion(ctx, http.DefaultClient, nil, "1.2")
+	g.Err(err)
+}
+
+func TestInstallVersionConstraint(t *testing.T) {
+	g := got.T(t)
+
+	s := g.Serve()
+	s.Mux.HandleFunc("/versions.json", func(rw http.ResponseWriter, r *http.Request) {
+		g.E(rw.Write([]byte(`["2.4.0", "2.5.0", "2.5.3", "3.0.0"]`)))
+	})
+	s.Mux.HandleFunc("/tool-2.5.3.tar.gz", func(rw http.ResponseWriter, r *http.Request) {
+		g.E(rw.Write(tarGz(g, map[string]string{"tool": "2.5.3"})))
+	})
+
+	dir := getTmpDir(g)
+
+	g.E(pkg.InstallWithOptions(pkg.Options{
+		Logger:       fetchup.LoggerQuiet,
+		InstallToDir: dir,
+		Version:      "^2.5",
+		Versions:     &pkg.IndexJSON{URL: s.URL("/versions.json")},
+		URLs:         pkg.NewTemplates(s.URL("/tool-{{.Version}}.tar.gz")),
+		BundleBin:    pkg.NewTemplates("tool"),
+	}))
+
+	g.Eq(g.Read(filepath.Join(dir, "tool")).String(), "2.5.3")
+}
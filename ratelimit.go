@@ -0,0 +1,104 @@
+package fetchup
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket to limit the download bandwidth.
+// It can be shared by multiple [Fetchup] instances to limit their total bandwidth.
+type RateLimiter struct {
+	rate  float64
+	burst int
+
+	lock   sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a limiter that allows bytesPerSec bytes per second.
+// A bytesPerSec of 0 or less means unlimited, it returns nil.
+func NewRateLimiter(bytesPerSec int) *RateLimiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+
+	burst := bytesPerSec / 10
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		rate:   float64(bytesPerSec),
+		burst:  burst,
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Reader wraps r so that reading from it consumes the tokens of the limiter.
+// It returns r as it is if the limiter is nil.
+func (l *RateLimiter) Reader(ctx context.Context, r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+
+	return &limitedReader{ctx, l, r}
+}
+
+// wait takes n tokens from the bucket, it blocks until the bucket has paid off the debt.
+func (l *RateLimiter) wait(ctx context.Context, n int) error {
+	l.lock.Lock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > float64(l.burst) {
+		l.tokens = float64(l.burst)
+	}
+	l.last = now
+	l.tokens -= float64(n)
+
+	var d time.Duration
+	if l.tokens < 0 {
+		d = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+
+	l.lock.Unlock()
+
+	if d == 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type limitedReader struct {
+	ctx context.Context
+	l   *RateLimiter
+	r   io.Reader
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if len(p) > lr.l.burst {
+		p = p[:lr.l.burst]
+	}
+
+	n, err := lr.r.Read(p)
+	if n > 0 {
+		if e := lr.l.wait(lr.ctx, n); e != nil {
+			return n, e
+		}
+	}
+
+	return n, err
+}
@@ -101,6 +101,28 @@ func setup(t *testing.T) (got.G, *got.Router, []byte) {
 		}
 	})
 
+	s.Mux.HandleFunc("/stall/", func(rw http.ResponseWriter, r *http.Request) {
+		g.E(rw.Write(data[:100]))
+		rw.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	s.Mux.HandleFunc("/trickle/", func(rw http.ResponseWriter, r *http.Request) {
+		for _, b := range data {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+
+			_, err := rw.Write([]byte{b})
+			if err != nil {
+				return
+			}
+			rw.(http.Flusher).Flush()
+		}
+	})
+
 	s.Mux.HandleFunc("/file/", func(rw http.ResponseWriter, r *http.Request) {
 		buf := bytes.NewBuffer(nil)
 		gz := gzip.NewWriter(buf)
@@ -0,0 +1,227 @@
+package fetchup
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Source opens the URLs of a scheme, such as "s3://bucket/key".
+type Source interface {
+	// Open returns the content of u.
+	// The client is the [Fetchup.HttpClient] with the [Fetchup.Auth] applied.
+	// Use [TargetFrom] with the ctx to get the platform to download for, such as to select from an image index.
+	Open(ctx context.Context, client *http.Client, u *url.URL) (*SourceResponse, error)
+}
+
+// Target is the platform to download for.
+type Target struct {
+	OS   string
+	Arch string
+}
+
+type targetKey struct{}
+
+// TargetFrom returns the [Fetchup.TargetOS] and [Fetchup.TargetArch] of the download that opens a [Source],
+// the empty ones default to [runtime.GOOS] and [runtime.GOARCH].
+func TargetFrom(ctx context.Context) Target {
+	t, _ := ctx.Value(targetKey{}).(Target)
+	if t.OS == "" {
+		t.OS = runtime.GOOS
+	}
+	if t.Arch == "" {
+		t.Arch = runtime.GOARCH
+	}
+	return t
+}
+
+// SourceResponse is the content returned by a [Source].
+type SourceResponse struct {
+	Body io.ReadCloser
+
+	// Size of the body, -1 if it's unknown.
+	Size int64
+
+	Header http.Header
+
+	// Name is the hint to detect the format of the body, check [Response.Name] for more details.
+	Name string
+}
+
+// DefaultSources returns the builtin sources for "http", "https", "file", "data", and "oci" schemes.
+func DefaultSources() map[string]Source {
+	return map[string]Source{
+		"http":  HTTPSource{},
+		"https": HTTPSource{},
+		"file":  FileSource{},
+		"data":  DataSource{},
+		"oci":   &OCISource{},
+	}
+}
+
+// ErrUnsupportedScheme is returned when no [Source] is registered for the scheme of a URL.
+type ErrUnsupportedScheme struct {
+	URL string
+}
+
+func (e *ErrUnsupportedScheme) Error() string {
+	return fmt.Sprintf("No source registered for the scheme of: %s", e.URL)
+}
+
+// source returns the source for the scheme of u, a plain local path uses the "file" source.
+func (fu *Fetchup) source(u string) (Source, *url.URL, error) {
+	parsed, err := url.Parse(u)
+	if filepath.VolumeName(u) != "" || (err == nil && parsed.Scheme == "") {
+		parsed = &url.URL{Scheme: "file", Path: filepath.ToSlash(u)}
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	src, has := fu.Sources[strings.ToLower(parsed.Scheme)]
+	if !has {
+		return nil, nil, &ErrUnsupportedScheme{u}
+	}
+
+	return src, parsed, nil
+}
+
+// ErrHTTPStatus is returned when the server responds with a non-2xx status code.
+type ErrHTTPStatus struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *ErrHTTPStatus) Error() string {
+	return fmt.Sprintf("Unexpected status code %d: %s", e.StatusCode, e.URL)
+}
+
+// HTTPSource downloads http and https URLs.
+type HTTPSource struct{}
+
+var _ Source = HTTPSource{}
+
+func (HTTPSource) Open(ctx context.Context, client *http.Client, u *url.URL) (*SourceResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return doRequest(client, req)
+}
+
+// doRequest sends the request and checks the status code.
+func doRequest(client *http.Client, req *http.Request) (*SourceResponse, error) {
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		_ = res.Body.Close()
+		return nil, &ErrHTTPStatus{req.URL.String(), res.StatusCode}
+	}
+
+	return &SourceResponse{
+		Body:   res.Body,
+		Size:   res.ContentLength,
+		Header: res.Header,
+	}, nil
+}
+
+// FileSource reads "file://" URLs, such as "file:///mirror/tool.tar.gz".
+type FileSource struct{}
+
+var _ Source = FileSource{}
+
+func (FileSource) Open(_ context.Context, _ *http.Client, u *url.URL) (*SourceResponse, error) {
+	p := u.Path
+
+	// file:///C:/dir on Windows
+	if runtime.GOOS == "windows" && len(p) > 2 && p[0] == '/' && p[2] == ':' {
+		p = p[1:]
+	}
+
+	// UNC path, such as file://server/share/file
+	if u.Host != "" && u.Host != "localhost" {
+		p = "//" + u.Host + p
+	}
+
+	f, err := os.Open(filepath.FromSlash(p))
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	if stat.IsDir() {
+		_ = f.Close()
+		return nil, fmt.Errorf("%s is a directory", p)
+	}
+
+	return &SourceResponse{
+		Body:   f,
+		Size:   stat.Size(),
+		Header: http.Header{},
+	}, nil
+}
+
+// DataSource decodes data URLs, such as "data:application/gzip;base64,H4sI...".
+type DataSource struct{}
+
+var _ Source = DataSource{}
+
+func (DataSource) Open(_ context.Context, _ *http.Client, u *url.URL) (*SourceResponse, error) {
+	s := u.Opaque
+
+	i := strings.Index(s, ",")
+	if i < 0 {
+		return nil, fmt.Errorf("invalid data URL, missing comma")
+	}
+	meta, data := s[:i], s[i+1:]
+
+	var b []byte
+	var err error
+
+	mediaType := strings.TrimSuffix(meta, ";base64")
+	if mediaType != meta {
+		b, err = base64.StdEncoding.DecodeString(data)
+	} else {
+		var s string
+		s, err = url.PathUnescape(data)
+		b = []byte(s)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode data URL: %w", err)
+	}
+
+	if i := strings.Index(mediaType, ";"); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+
+	return &SourceResponse{
+		Body:   io.NopCloser(bytes.NewReader(b)),
+		Size:   int64(len(b)),
+		Header: http.Header{"Content-Type": {mediaType}},
+		Name:   "data" + mediaTypeExt[mediaType],
+	}, nil
+}
+
+var mediaTypeExt = map[string]string{
+	"application/gzip":   ".gz",
+	"application/x-gzip": ".gz",
+	"application/x-tar":  ".tar",
+	"application/x-gtar": ".tar.gz",
+	"application/zip":    ".zip",
+}
@@ -0,0 +1,177 @@
+package fetchup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrStalled is returned when a download is aborted because the remote is too slow or stops responding.
+type ErrStalled struct {
+	URL    string
+	Reason string
+}
+
+func (e *ErrStalled) Error() string {
+	return fmt.Sprintf("Download stalled, %s: %s", e.Reason, e.URL)
+}
+
+// watchdog cancels the request when one of the stall limits of the [Fetchup] is reached.
+type watchdog struct {
+	url    string
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	idleTimeout time.Duration
+	minSpeed    int
+	window      time.Duration
+
+	count int64
+
+	lock sync.Mutex
+	err  *ErrStalled
+	idle *time.Timer
+	done chan struct{}
+	once sync.Once
+}
+
+func newWatchdog(ctx context.Context, cancel context.CancelFunc, fu *Fetchup, u string) *watchdog {
+	return &watchdog{
+		url:         u,
+		ctx:         ctx,
+		cancel:      cancel,
+		idleTimeout: fu.IdleTimeout,
+		minSpeed:    fu.MinSpeed,
+		window:      fu.MinSpeedWindow,
+		done:        make(chan struct{}),
+	}
+}
+
+func (w *watchdog) trip(reason string) {
+	w.lock.Lock()
+	if w.err == nil {
+		w.err = &ErrStalled{URL: w.url, Reason: reason}
+	}
+	w.lock.Unlock()
+
+	w.cancel()
+}
+
+// stalled returns the stall error if the watchdog has tripped.
+func (w *watchdog) stalled() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.err == nil {
+		return nil
+	}
+	return w.err
+}
+
+// header limits the time to wait for the response header, call the returned function once the header arrives.
+func (w *watchdog) header(timeout time.Duration) (stop func()) {
+	if timeout <= 0 {
+		return func() {}
+	}
+
+	t := time.AfterFunc(timeout, func() {
+		w.trip(fmt.Sprintf("no response header in %v", timeout))
+	})
+
+	return func() { t.Stop() }
+}
+
+// watch starts to monitor the body reading.
+func (w *watchdog) watch(body io.Reader) io.Reader {
+	if w.idleTimeout > 0 {
+		w.idle = time.AfterFunc(w.idleTimeout, func() {
+			w.trip(fmt.Sprintf("no data received in %v", w.idleTimeout))
+		})
+	}
+
+	if w.minSpeed > 0 && w.window > 0 {
+		go w.checkSpeed()
+	}
+
+	return &stallReader{w, body}
+}
+
+type sample struct {
+	at    time.Time
+	count int64
+}
+
+// checkSpeed calculates the average speed over a sliding window.
+func (w *watchdog) checkSpeed() {
+	tick := time.NewTicker(w.window / 4)
+	defer tick.Stop()
+
+	samples := []sample{{time.Now(), 0}}
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case now := <-tick.C:
+			count := atomic.LoadInt64(&w.count)
+			samples = append(samples, sample{now, count})
+
+			for len(samples) > 1 && now.Sub(samples[1].at) >= w.window {
+				samples = samples[1:]
+			}
+
+			span := now.Sub(samples[0].at)
+			if span < w.window {
+				continue
+			}
+
+			speed := float64(count-samples[0].count) / span.Seconds()
+			if speed < float64(w.minSpeed) {
+				w.trip(fmt.Sprintf("speed %.0fB/s is lower than %dB/s", speed, w.minSpeed))
+				return
+			}
+		}
+	}
+}
+
+func (w *watchdog) stop() {
+	w.once.Do(func() {
+		close(w.done)
+		if w.idle != nil {
+			w.idle.Stop()
+		}
+	})
+}
+
+type stallReader struct {
+	w *watchdog
+	r io.Reader
+}
+
+func (s *stallReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+
+	if n > 0 {
+		atomic.AddInt64(&s.w.count, int64(n))
+		if s.w.idle != nil {
+			s.w.idle.Reset(s.w.idleTimeout)
+		}
+	}
+
+	// The body is complete, a slow consumer after this point isn't a stall.
+	if err == io.EOF {
+		s.w.stop()
+		return n, err
+	}
+
+	if err != nil {
+		if e := s.w.stalled(); e != nil {
+			return n, e
+		}
+	}
+
+	return n, err
+}
@@ -0,0 +1,139 @@
+package fetchup
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// TLSOptions to trust extra CAs and to use client certificates.
+type TLSOptions struct {
+	// CAFiles are the PEM bundles to trust in addition to the system roots.
+	CAFiles []string
+
+	// CADirs are the dirs that contain PEM bundles, all the files in them will be loaded.
+	// Files that contain no certificate, such as a README or a CRL, are skipped.
+	CADirs []string
+
+	// CAEnv is the list of env vars that point to a PEM bundle or a dir of them.
+	// If it's nil, SSL_CERT_FILE and SSL_CERT_DIR will be used.
+	CAEnv []string
+
+	// CertFile and KeyFile are the PEM files of the client certificate for mTLS.
+	CertFile string
+	KeyFile  string
+}
+
+// Config loads the files and returns the TLS config.
+func (o *TLSOptions) Config() (*tls.Config, error) {
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+
+	files := append([]string{}, o.CAFiles...)
+	dirs := append([]string{}, o.CADirs...)
+
+	env := o.CAEnv
+	if env == nil {
+		env = []string{"SSL_CERT_FILE", "SSL_CERT_DIR"}
+	}
+
+	for _, name := range env {
+		for _, p := range filepath.SplitList(os.Getenv(name)) {
+			stat, err := os.Stat(p)
+			if err != nil {
+				continue
+			}
+
+			if stat.IsDir() {
+				dirs = append(dirs, p)
+			} else {
+				files = append(files, p)
+			}
+		}
+	}
+
+	for _, p := range files {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+
+		if !roots.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificate found in %s", p)
+		}
+	}
+
+	for _, dir := range dirs {
+		list, err := readDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA dir: %w", err)
+		}
+
+		for _, f := range list {
+			if f.IsDir() {
+				continue
+			}
+
+			// A cert dir usually has other files in it, only the certificates matter
+			b, err := os.ReadFile(filepath.Join(dir, f.Name()))
+			if err == nil {
+				roots.AppendCertsFromPEM(b)
+			}
+		}
+	}
+
+	cfg := &tls.Config{RootCAs: roots}
+
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// WithTLS returns a clone that uses the TLS options for the connections.
+// The transport of the HttpClient must be a [DefaultTransport] whose Base is nil or an [http.Transport].
+func (fu *Fetchup) WithTLS(opts *TLSOptions) (*Fetchup, error) {
+	cfg, err := opts.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	dt, ok := fu.HttpClient.Transport.(*DefaultTransport)
+	if !ok {
+		return nil, fmt.Errorf("TLS options require the transport to be *fetchup.DefaultTransport, got %T", fu.HttpClient.Transport)
+	}
+
+	base := dt.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	ht, ok := base.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("TLS options require the base transport to be *http.Transport, got %T", base)
+	}
+
+	ht = ht.Clone()
+	ht.TLSClientConfig = cfg
+
+	t := *dt
+	t.Base = ht
+
+	c := *fu.HttpClient
+	c.Transport = &t
+
+	n := fu.clone()
+	n.HttpClient = &c
+	return n, nil
+}
@@ -0,0 +1,66 @@
+package fetchup_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/ysmood/fetchup"
+)
+
+func TestTLS(t *testing.T) {
+	g, _, data := setup(t)
+
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		g.E(rw.Write(data))
+	}))
+	s.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	s.StartTLS()
+	defer s.Close()
+
+	dir := getTmpDir(g)
+	cert := s.TLS.Certificates[0]
+	key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	g.E(err)
+	certFile := filepath.Join(dir, "ca", "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	g.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}))
+	g.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}))
+	g.WriteFile(filepath.Join(dir, "ca", "README"), "not a certificate")
+
+	p := filepath.Join(dir, "t.out")
+
+	fu := fetchup.New(s.URL + "/file").WithSaveTo(p)
+	fu.Logger = log.New(io.Discard, "", 0)
+	fu.SpeedPacketSize = 100
+
+	g.Err(fu.Fetch())
+
+	g.Setenv("FETCHUP_TEST_CA", filepath.Dir(certFile))
+
+	secure, err := fu.WithTLS(&fetchup.TLSOptions{CAEnv: []string{"FETCHUP_TEST_CA"}})
+	g.E(err)
+	g.Err(secure.Fetch())
+
+	secure, err = fu.WithTLS(&fetchup.TLSOptions{CAFiles: []string{certFile}, CertFile: certFile, KeyFile: keyFile})
+	g.E(err)
+	g.E(secure.Fetch())
+	g.Eq(g.Read(p).Bytes(), data)
+
+	secure, err = fu.WithTLS(&fetchup.TLSOptions{CADirs: []string{filepath.Dir(certFile)}, CertFile: certFile, KeyFile: keyFile})
+	g.E(err)
+	g.E(secure.Fetch())
+
+	_, err = fu.WithTLS(&fetchup.TLSOptions{CAFiles: []string{keyFile}})
+	g.Err(err)
+}
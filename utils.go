@@ -9,7 +9,9 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"runtime/debug"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -184,15 +186,87 @@ func normalizePath(p string) string {
 
 // DefaultTransport is the default http transport for fetchup, it auto handles the gzip and user-agent.
 type DefaultTransport struct {
+	// UA is the User-Agent header, default is [UserAgent].
 	UA string
+
+	// Header is the extra headers to set if the request doesn't have them.
+	Header http.Header
+
+	// Base is the transport to send the requests, default is [http.DefaultTransport].
+	// Use it to customize the proxy, TLS, etc.
+	Base http.RoundTripper
 }
 
 var _ http.RoundTripper = (*DefaultTransport)(nil)
 
 func (t *DefaultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	req.Header.Set("User-Agent", t.UA)
+	// RoundTrip should not modify the request
+	req = req.Clone(req.Context())
+
+	for k, list := range t.Header {
+		if _, has := req.Header[k]; has {
+			continue
+		}
+		for _, v := range list {
+			req.Header.Add(k, v)
+		}
+	}
+
+	ua := t.UA
+	if ua == "" {
+		ua = UserAgent()
+	}
+
+	req.Header.Set("User-Agent", ua)
 	req.Header.Set("Accept-Encoding", "gzip")
-	return http.DefaultTransport.RoundTrip(req)
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return base.RoundTrip(req)
+}
+
+// UserAgent returns "fetchup/<version>", the version is read from the build info of the binary.
+func UserAgent() string {
+	userAgentOnce.Do(func() {
+		userAgent = "fetchup/" + moduleVersion()
+	})
+	return userAgent
+}
+
+var (
+	userAgentOnce sync.Once
+	userAgent     string
+)
+
+func moduleVersion() string {
+	version := "dev"
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		mods := append([]*debug.Module{&info.Main}, info.Deps...)
+		for _, m := range mods {
+			if m.Path == modulePath && m.Version != "" && m.Version != "(devel)" {
+				version = strings.TrimPrefix(m.Version, "v")
+				break
+			}
+		}
+	}
+
+	return version
+}
+
+const modulePath = "github.com/ysmood/fetchup"
+
+func without(list []string, item string) []string {
+	rest := []string{}
+	for _, s := range list {
+		if s != item {
+			rest = append(rest, s)
+		}
+	}
+	return rest
 }
 
 func randStr(n int) string {